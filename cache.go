@@ -0,0 +1,45 @@
+package goignore
+
+import "sync"
+
+// ConcurrentCachedMatcher wraps a GitIgnore, and caches the result for every path it was asked about
+// it is safe for concurrent use, unlike GitIgnore itself
+// the cache is never evicted, so it grows with the number of distinct paths
+type ConcurrentCachedMatcher struct {
+	ignore *GitIgnore
+	cache  sync.Map
+	bufs   sync.Pool
+}
+
+// Creates a ConcurrentCachedMatcher for the gitignore
+// the gitignore must not be modified while the matcher is in use
+func NewConcurrentCachedMatcher(g *GitIgnore) *ConcurrentCachedMatcher {
+	return &ConcurrentCachedMatcher{
+		ignore: g,
+		bufs: sync.Pool{
+			New: func() any {
+				buf := make([]string, 2048)
+				return &buf
+			},
+		},
+	}
+}
+
+// Same as GitIgnore.MatchesPath, but the result is cached
+func (m *ConcurrentCachedMatcher) MatchesPath(path string) bool {
+	if matched, ok := m.cache.Load(path); ok {
+		return matched.(bool)
+	}
+
+	// every goroutine needs its own buffer for splitting the path
+	buf := m.bufs.Get().(*[]string)
+	matched := false
+	if pathComponents, isDir, ok := m.ignore.splitPathBuf(path, *buf); ok {
+		deciding := m.ignore.decidingRule(pathComponents, isDir)
+		matched = deciding != -1 && !m.ignore.Rules[deciding].Negate
+	}
+	m.bufs.Put(buf)
+
+	m.cache.Store(path, matched)
+	return matched
+}
@@ -0,0 +1,55 @@
+package goignore
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrentCachedMatcher(t *testing.T) {
+	ignoreObject := CompileIgnoreLines(realisticRules)
+	matcher := NewConcurrentCachedMatcher(ignoreObject)
+
+	expected := make([]bool, len(realisticPaths))
+	for i, path := range realisticPaths {
+		expected[i] = ignoreObject.MatchesPath(path)
+	}
+
+	// run with -race to check for data races
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := 0; round < 20; round++ {
+				for i, path := range realisticPaths {
+					assert.Equal(t, expected[i], matcher.MatchesPath(path), "should agree with MatchesPath on "+path)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkConcurrentCachedMatcher(b *testing.B) {
+	for _, parallelism := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("parallelism=%d", parallelism), func(b *testing.B) {
+			matcher := NewConcurrentCachedMatcher(CompileIgnoreLines(realisticRules))
+			for _, path := range realisticPaths {
+				matcher.MatchesPath(path)
+			}
+
+			b.SetParallelism(parallelism)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					matcher.MatchesPath(realisticPaths[i%len(realisticPaths)])
+					i++
+				}
+			})
+		})
+	}
+}
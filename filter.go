@@ -0,0 +1,74 @@
+package goignore
+
+import (
+	"bufio"
+	"io"
+	"path"
+)
+
+// Reads newline separated paths from in, and writes the ones that are not ignored to out
+// the paths are processed one by one, so the stream can be arbitrarily large
+func (g *GitIgnore) FilterStream(in io.Reader, out io.Writer) error {
+	return g.FilterStreamDelim(in, out, '\n')
+}
+
+// Same as FilterStream, but the paths are separated by delim, use 0 for the output of `find -print0`
+// empty paths are dropped, the kept paths are written with delim after each of them
+// if delim is '\n', the '\r' of a CRLF line ending is dropped too
+func (g *GitIgnore) FilterStreamDelim(in io.Reader, out io.Writer, delim byte) error {
+	reader := bufio.NewReader(in)
+	writer := bufio.NewWriter(out)
+
+	for {
+		path, err := reader.ReadString(delim)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		eof := err == io.EOF
+
+		if len(path) > 0 && path[len(path)-1] == delim {
+			path = path[:len(path)-1]
+		}
+		if delim == '\n' && len(path) > 0 && path[len(path)-1] == '\r' {
+			path = path[:len(path)-1]
+		}
+		if path != "" && !g.MatchesPath(path) {
+			if _, err := writer.WriteString(path); err != nil {
+				return err
+			}
+			if err := writer.WriteByte(delim); err != nil {
+				return err
+			}
+		}
+
+		if eof {
+			break
+		}
+	}
+
+	return writer.Flush()
+}
+
+// Returns the paths that are not ignored, in their original order
+func (g *GitIgnore) FilterPaths(paths []string) []string {
+	var kept []string
+	for _, path := range paths {
+		if !g.MatchesPath(path) {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+// Returns the names of the entries in the directory listing that are not ignored, in their original order
+// dir is the path of the directory relative to the root of the gitignore, "" for the root itself
+// dirNames tells which of the names are directories, so directory-only rules apply to them
+func (g *GitIgnore) KeepInDir(dir string, names []string, dirNames map[string]bool) []string {
+	var kept []string
+	for _, name := range names {
+		if !g.MatchesEntry(path.Join(dir, name), dirNames[name]) {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
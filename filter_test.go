@@ -0,0 +1,53 @@
+package goignore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterStream(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "build/", "!keep.log"})
+	in := strings.NewReader("src/main.go\na.log\nkeep.log\nbuild/x\n\nbuild/\nREADME.md")
+	var out bytes.Buffer
+
+	err := ignoreObject.FilterStream(in, &out)
+	assert.Nil(t, err, "should filter the stream")
+	assert.Equal(t, "src/main.go\nkeep.log\nREADME.md\n", out.String(), "should only keep the paths that are not ignored")
+
+	out.Reset()
+	err = ignoreObject.FilterStream(strings.NewReader("a.log\r\nb.txt\r\n\r\nc.log\r"), &out)
+	assert.Nil(t, err, "should filter the stream")
+	assert.Equal(t, "b.txt\n", out.String(), "should drop the '\\r' of CRLF line endings")
+}
+
+func TestFilterStreamDelim(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log"})
+	in := strings.NewReader("src/main.go\x00a.log\x00with\nnewline.txt\x00")
+	var out bytes.Buffer
+
+	err := ignoreObject.FilterStreamDelim(in, &out, 0)
+	assert.Nil(t, err, "should filter the stream")
+	assert.Equal(t, "src/main.go\x00with\nnewline.txt\x00", out.String(), "should keep NUL separated paths")
+}
+
+func TestFilterPaths(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "build/", "!keep.log"})
+	paths := []string{"src/main.go", "a.log", "keep.log", "build/x", "build/", "README.md"}
+
+	assert.Equal(t, []string{"src/main.go", "keep.log", "README.md"}, ignoreObject.FilterPaths(paths), "should only keep the paths that are not ignored")
+}
+
+func TestKeepInDir(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.tmp", "build/"})
+	names := []string{"main.go", "a.tmp", "build", "docs", "notes.tmp", "README.md"}
+	dirNames := map[string]bool{"build": true, "docs": true, "notes.tmp": true}
+
+	assert.Equal(t, []string{"main.go", "docs", "README.md"}, ignoreObject.KeepInDir("", names, dirNames), "should drop the ignored entries of the root")
+	assert.Equal(t, []string{"main.go", "docs", "README.md"}, ignoreObject.KeepInDir("src/pkg", names, dirNames), "should drop the ignored entries of src/pkg")
+
+	// build is a file here, so the directory-only rule does not apply
+	assert.Equal(t, []string{"main.go", "build"}, ignoreObject.KeepInDir("src", []string{"main.go", "build"}, nil), "should keep the file build")
+}
@@ -0,0 +1,216 @@
+package goignore
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runs git with a clean environment, so the user's global excludes don't leak into the results
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_CONFIG_NOSYSTEM=1",
+	)
+	return cmd.Run()
+}
+
+// Asks git whether the path is ignored by the .gitignore in dir
+// the path is created in the work tree first, so git knows if it is a directory, a trailing '/' marks a directory
+// `git check-ignore` gives inconsistent results for paths with a trailing '/' that don't exist
+func gitCheckIgnore(t *testing.T, dir string, path string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("could not read %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != ".git" && entry.Name() != ".gitignore" {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				t.Fatalf("could not clean %s: %v", dir, err)
+			}
+		}
+	}
+
+	writeTree(t, dir, map[string]string{path: ""})
+	return gitCheckIgnored(t, dir, strings.TrimSuffix(path, "/"))
+}
+
+// Asks git whether the path is ignored by the repository in dir, without touching the work tree
+func gitCheckIgnored(t *testing.T, dir string, path string) bool {
+	err := runGit(dir, "check-ignore", "-q", "--no-index", "--", path)
+	if err == nil {
+		return true
+	}
+
+	// exit code 1 means the path is not ignored, anything else is a real failure
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false
+	}
+	t.Fatalf("git check-ignore failed for %q: %v", path, err)
+	return false
+}
+
+// CompareWithGit asserts that our matcher and `git check-ignore` agree on every path
+// the test is skipped if git is not installed
+func CompareWithGit(t *testing.T, patterns []string, paths []string) {
+	t.Helper()
+	ignoreObject := CompileIgnoreLines(patterns)
+	compareWithGit(t, patterns, paths, ignoreObject.MatchesPath)
+}
+
+// Same as CompareWithGit, but uses MatchesPathGit
+func CompareWithGitFaithful(t *testing.T, patterns []string, paths []string) {
+	t.Helper()
+	ignoreObject := CompileIgnoreLines(patterns)
+	compareWithGit(t, patterns, paths, func(path string) bool {
+		return ignoreObject.MatchesPathGit(path, false)
+	})
+}
+
+// writes the patterns to a .gitignore, and compares the results of matches to `git check-ignore`
+func compareWithGit(t *testing.T, patterns []string, paths []string, matches func(string) bool) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	if err := runGit(dir, "init", "-q"); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+
+	writeTree(t, dir, map[string]string{".gitignore": strings.Join(patterns, "\n") + "\n"})
+
+	for _, path := range paths {
+		assert.Equal(t, gitCheckIgnore(t, dir, path), matches(path), "should agree with git on "+path)
+	}
+}
+
+func TestGit_HandleIncludePattern(t *testing.T) {
+	CompareWithGit(t, []string{
+		"/*",
+		"!/foo",
+		"/foo/*",
+		"!/foo/bar",
+	}, []string{"a", "foo/baz", "foo", "foo/bar", "foo/bar/x", "b/c"})
+}
+
+func TestGit_DirOnly(t *testing.T) {
+	CompareWithGit(t, []string{"foo/", "/build/"}, []string{
+		"foo", "foo/", "foo/bar", "a/foo/", "a/foo/bar",
+		"build", "build/", "build/x", "a/build/",
+	})
+}
+
+func TestGit_DoubleStar(t *testing.T) {
+	CompareWithGit(t, []string{"**/foo", "baz/**", "a/**/b"}, []string{
+		"foo", "x/y/foo", "baz/buzz", "baz/a/b", "a/b", "a/x/b", "a/x/y/b", "x/a/b",
+	})
+}
+
+func TestGit_CharacterClasses(t *testing.T) {
+	CompareWithGit(t, []string{"[a-c]-files", "[!0-9].txt", "[[:digit:]].log"}, []string{
+		"a-files", "d-files", "x.txt", "5.txt", "5.log", "x.log",
+	})
+}
+
+func TestGit_Escaping(t *testing.T) {
+	CompareWithGit(t, []string{"\\#file.txt", "\\!file.txt", "\\[hello"}, []string{
+		"#file.txt", "!file.txt", "[hello", "hello",
+	})
+}
+
+func TestGit_CharacterClassLiteralDash(t *testing.T) {
+	CompareWithGit(t, []string{"[a-]", "x[-b]"}, []string{"a", "-", "b", "x-", "xb", "xa"})
+}
+
+func TestGit_DoubleStarDirOnly(t *testing.T) {
+	CompareWithGit(t, []string{"a/**/"}, []string{"a", "a/", "a/b", "a/b/", "a/b/c/", "a/b/c", "x/a/b/"})
+	CompareWithGit(t, []string{"**/a/**"}, []string{"a", "x/a/y", "a/y", "x/a", "x/y"})
+}
+
+func TestGit_NegationOrder(t *testing.T) {
+	CompareWithGit(t, []string{"!keep", "*"}, []string{"keep", "other"})
+	CompareWithGit(t, []string{"*", "!keep"}, []string{"keep", "other"})
+}
+
+func TestGit_StarDirOnly(t *testing.T) {
+	CompareWithGit(t, []string{"*/"}, []string{"foo/", "foo/bar", "a/foo/", "foo", "a/foo"})
+}
+
+func TestGit_ParentExclusion(t *testing.T) {
+	patterns := []string{"build/", "!build/keep", "!build/keep/"}
+	paths := []string{"build/", "build/keep", "build/keep/", "build/keep/x", "build/other", "src/build/keep"}
+	CompareWithGitFaithful(t, patterns, paths)
+
+	ignoreObject := CompileIgnoreLines(patterns)
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/keep"), "MatchesPath should still re-include build/keep")
+	assert.Equal(t, true, ignoreObject.MatchesPathGit("build/keep", false), "MatchesPathGit should not re-include build/keep")
+}
+
+func TestGit_MultiLevelReinclude(t *testing.T) {
+	CompareWithGitFaithful(t, []string{
+		"/*",
+		"!/foo",
+		"/foo/*",
+		"!/foo/bar",
+	}, []string{"a", "a/b", "foo", "foo/", "foo/baz", "foo/baz/x", "foo/bar", "foo/bar/", "foo/bar/x", "foo/bar/x/y"})
+
+	CompareWithGitFaithful(t, []string{
+		"*",
+		"!*/",
+		"!*.go",
+		"vendor/",
+	}, []string{"README", "main.go", "src/", "src/main.go", "src/data.bin", "vendor/", "vendor/a.go", "src/vendor/b.go"})
+}
+
+func TestGit_EntryOnlyMatching(t *testing.T) {
+	// "b" matches a/b by its name, "!a/" only re-includes a itself
+	CompareWithGitFaithful(t, []string{"b", "!a/", "x/**", "!x/y"}, []string{
+		"a/", "a/b", "a/c", "x/", "x/y", "x/z", "x/y/z",
+	})
+
+	ignoreObject := CompileIgnoreLines([]string{"build/"})
+	assert.Equal(t, true, ignoreObject.MatchesPathGit("build", true), "should match the directory build")
+	assert.Equal(t, false, ignoreObject.MatchesPathGit("build", false), "should not match the file build")
+}
+
+func TestGit_DoubledSlashes(t *testing.T) {
+	// git never matches doubled slashes, which is what ExactSlashes does
+	patterns := []string{"a//b", "x/y"}
+	ignoreObject := CompileIgnoreLinesWithOptions(patterns, Options{ExactSlashes: true})
+	compareWithGit(t, patterns, []string{"a/b", "x/y", "x/y/z"}, func(path string) bool {
+		return ignoreObject.MatchesPathGit(path, false)
+	})
+}
+
+func TestGit_TrailingDoubleStar(t *testing.T) {
+	paths := []string{"vendor", "vendor/", "vendor/a", "vendor/a/", "vendor/a/b"}
+	CompareWithGit(t, []string{"vendor/**"}, paths)
+	CompareWithGit(t, []string{"vendor/"}, paths)
+	CompareWithGitFaithful(t, []string{"vendor/**"}, paths)
+	CompareWithGitFaithful(t, []string{"vendor/"}, paths)
+}
+
+func TestGit_CharacterClassEscapes(t *testing.T) {
+	CompareWithGit(t, []string{"[\\]a]x", "y[a-\\c]", "z[\\-]"}, []string{"]x", "ax", "bx", "\\x", "yb", "yc", "yd", "z-", "z\\"})
+}
+
+func TestGit_EmbeddedSlash(t *testing.T) {
+	CompareWithGit(t, []string{"foo/bar"}, []string{"foo/bar", "foo/bar/", "foo/bar/baz", "x/foo/bar", "foo/barbaz", "foo"})
+}
+
+func TestGit_ExactRules(t *testing.T) {
+	paths := []string{"config", "config/", "config/x", "a/config", "a/config/", "a/config/x"}
+	CompareWithGit(t, []string{"config"}, paths)
+	CompareWithGit(t, []string{"config/"}, paths)
+}
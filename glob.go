@@ -0,0 +1,73 @@
+package goignore
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Returns the rules as anchored globs for libraries like doublestar, for example "**/foo" becomes "root/**/foo"
+// every rule also matches the contents of what it matches, so a "/**" glob is added for each of them,
+// directory-only rules only get the "/**" glob, since a glob can't tell directories apart from files
+// negations can't be expressed as globs, so they are left out, this is only equivalent if HasNegations() is false
+// rules compiled in RegexMode are left out too, and FoldFiles, FoldDirectories and NormalizeUnicode are not carried into the globs
+// '{' and '}' are escaped, since they are alternatives in glob libraries but literals in .gitignore files
+// root is the directory the gitignore is in, "" leaves the globs relative
+func (g *GitIgnore) ToGlobs(root string) []string {
+	prefix := strings.TrimSuffix(filepath.ToSlash(root), "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	var globs []string
+	for i := range g.Rules {
+		rule := &g.Rules[i]
+		if rule.Negate || len(rule.Components) == 0 {
+			continue
+		}
+
+		glob := prefix
+		if !rule.Relative {
+			glob += "**/"
+		}
+		for j, component := range rule.Components {
+			if j > 0 {
+				glob += "/"
+			}
+			glob += escapeBraces(component)
+		}
+
+		if rule.Components[len(rule.Components)-1] == "**" {
+			// already matches everything inside
+			globs = append(globs, glob)
+			continue
+		}
+		if !rule.OnlyDirectory {
+			globs = append(globs, glob)
+		}
+		globs = append(globs, glob+"/**")
+	}
+	return globs
+}
+
+// escapes the '{' and '}' of a pattern component, characters that are already escaped are left alone
+func escapeBraces(component string) string {
+	if !strings.ContainsAny(component, "{}") {
+		return component
+	}
+	var b strings.Builder
+	for i := 0; i < len(component); i++ {
+		switch component[i] {
+		case '\\':
+			b.WriteByte('\\')
+			if i+1 < len(component) {
+				i++
+				b.WriteByte(component[i])
+			}
+			continue
+		case '{', '}':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(component[i])
+	}
+	return b.String()
+}
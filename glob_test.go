@@ -0,0 +1,26 @@
+package goignore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToGlobs(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "**/foo", "/dist", "a/b", "build/", "vendor/**", "!keep.log", "a/**/c"})
+
+	assert.Equal(t, []string{
+		"root/**/*.log", "root/**/*.log/**",
+		"root/**/foo", "root/**/foo/**",
+		"root/dist", "root/dist/**",
+		"root/a/b", "root/a/b/**",
+		"root/**/build/**",
+		"root/vendor/**",
+		"root/a/**/c", "root/a/**/c/**",
+	}, ignoreObject.ToGlobs("root/"), "should emit anchored globs for every rule that is not a negation")
+
+	assert.Equal(t, []string{"**/*.log", "**/*.log/**", "dist", "dist/**"}, CompileIgnoreLines([]string{"*.log", "/dist"}).ToGlobs(""), "should leave the globs relative without a root")
+	assert.Equal(t, 0, len(CompileIgnoreLines([]string{"!keep"}).ToGlobs("root")), "should leave out negations")
+	assert.Equal(t, 0, len(CompileIgnoreLines([]string{"/"}).ToGlobs("root")), "should leave out rules without components")
+	assert.Equal(t, []string{"\\{a,b\\}/**", "**/x\\{\\}.txt", "**/x\\{\\}.txt/**"}, CompileIgnoreLines([]string{"/{a,b}/", "x\\{}.txt"}).ToGlobs(""), "should escape braces")
+}
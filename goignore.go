@@ -1,10 +1,21 @@
 package goignore
 
 import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // this is my own implementation of strings.Split()
@@ -70,11 +81,44 @@ func mySplit(s string, sep byte) []string {
 // Negate is true if the rule negates the match (i.e. starts with '!')
 // OnlyDirectory is true if the rule matches only directories (i.e. ends with '/')
 // Relative is true if the rule is relative (i.e. starts with '/')
+// Pattern is the pattern the rule was created from, after trimming whitespace
+// Line is the 1-based line number of the pattern in its source
+// Source is the file the pattern was read from, empty if the rule was not compiled from a file
+// Layer is the index of the source the rule came from when gitignores are merged, 0 otherwise
+// patterns added to a merged gitignore get a layer after the ones of its sources
+// exact is true for rules that are not relative and have a single component without wildcards or escapes
+// regex is the compiled pattern of rules created in RegexMode, nil otherwise
+// leadingDoubleStar is true if a leading "**" was dropped from the components of the pattern
 type Rule struct {
-	Components    []string
-	Negate        bool
-	OnlyDirectory bool
-	Relative      bool
+	Components        []string
+	Negate            bool
+	OnlyDirectory     bool
+	Relative          bool
+	Pattern           string
+	Line              int
+	Source            string
+	Layer             int
+	exact             bool
+	regex             *regexp.Regexp
+	leadingDoubleStar bool
+}
+
+// reports whether the rule contains no wildcards, so it can only match by name
+func (r *Rule) isLiteral() bool {
+	if r.regex != nil || r.leadingDoubleStar {
+		return false
+	}
+	for _, component := range r.Components {
+		for i := 0; i < len(component); i++ {
+			switch component[i] {
+			case '\\':
+				i++ // skip the escaped character
+			case '*', '?', '[':
+				return false
+			}
+		}
+	}
+	return true
 }
 
 func selectorMatch(c byte, selector string) bool {
@@ -108,11 +152,64 @@ func selectorMatch(c byte, selector string) bool {
 	}
 }
 
+// compares two bytes, ignoring ASCII case if fold is true
+func byteEqual(a, b byte, fold bool) bool {
+	if a == b {
+		return true
+	}
+	return fold && toLower(a) == toLower(b)
+}
+
+// reports whether the runes are the same under Unicode simple case folding, like strings.EqualFold
+func equalFoldRune(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
+func toLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func toUpper(c byte) byte {
+	if 'a' <= c && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+	return c
+}
+
+// matches a single path component against a single component of a pattern
+// the whole component has to match, descendants of a matching directory are handled by matchComponents
 func stringMatch(str string, pattern string) bool {
+	return stringMatchFold(str, pattern, false, 0)
+}
+
+// Reports whether name matches the shell pattern, like filepath.Match, but with the syntax of .gitignore patterns
+// name is a single path component, there is no special handling of '/'
+// unlike filepath.Match, this supports POSIX classes like [[:digit:]], '!' negates a class like '^',
+// a leading ']' in a class is a literal, invalid patterns simply don't match, and '?' matches a single byte, not a rune
+func MatchSegment(pattern string, name string) bool {
+	return stringMatch(name, pattern)
+}
+
+// same as stringMatch, but ignores case if fold is true
+// literals are folded with Unicode simple case folding, which does not depend on the locale, character classes only fold ASCII
+// if maxBacktrack is not zero, the match fails after backtracking to a '*' more than maxBacktrack times
+func stringMatchFold(str string, pattern string, fold bool, maxBacktrack int) bool {
 	// i is the index in str, j is the index in pattern
 	i, j := 0, 0
 	lastStarIdx := -1
 	lastStrIdx := -1
+	backtracks := 0
 
 	matchCharClass := func(j int, ch byte) (match bool, newJ int, ok bool) {
 		j++ // skip '['
@@ -138,11 +235,6 @@ func stringMatch(str string, pattern string) bool {
 		}
 
 		for j < len(pattern) && pattern[j] != ']' {
-			// handle escaping
-			if pattern[j] == '\\' && j+1 < len(pattern) {
-				j += 2
-				continue
-			}
 			// handle special [:class:] character classes
 			if j+2 < len(pattern) && pattern[j] == '[' && pattern[j+1] == ':' {
 				j += 2
@@ -163,20 +255,33 @@ func stringMatch(str string, pattern string) bool {
 				j = s + 1
 				continue
 			}
-			// handle ranges
-			if j+2 < len(pattern) && pattern[j+1] == '-' && pattern[j+2] != ']' {
-				a := pattern[j]
-				b := pattern[j+2]
+			// handle escaping, the escaped character is matched literally
+			a, width := pattern[j], 1
+			if a == '\\' && j+1 < len(pattern) {
+				a, width = pattern[j+1], 2
+			}
+			// handle ranges, both ends can be escaped
+			if k := j + width + 1; k < len(pattern) && pattern[j+width] == '-' && pattern[k] != ']' {
+				b := pattern[k]
+				if b == '\\' && k+1 < len(pattern) {
+					k++
+					b = pattern[k]
+				}
 				if a <= ch && ch <= b {
 					matched = true
+				} else if fold {
+					lower, upper := toLower(ch), toUpper(ch)
+					if a <= lower && lower <= b || a <= upper && upper <= b {
+						matched = true
+					}
 				}
-				j += 3
+				j = k + 1
 				continue
 			}
-			if pattern[j] == ch {
+			if byteEqual(a, ch, fold) {
 				matched = true
 			}
-			j++
+			j += width
 		}
 
 		if j >= len(pattern) || pattern[j] != ']' {
@@ -224,7 +329,16 @@ func stringMatch(str string, pattern string) bool {
 					j++
 					pChar = pattern[j]
 				}
-				if str[i] == pChar {
+				if fold && (str[i] >= utf8.RuneSelf || pChar >= utf8.RuneSelf) {
+					// fold whole runes, so non-ASCII letters match their other case
+					strRune, strWidth := utf8.DecodeRuneInString(str[i:])
+					patternRune, patternWidth := utf8.DecodeRuneInString(pattern[j:])
+					if equalFoldRune(strRune, patternRune) {
+						i += strWidth
+						j += patternWidth
+						continue
+					}
+				} else if byteEqual(str[i], pChar, fold) {
 					i++
 					j++
 					continue
@@ -233,6 +347,10 @@ func stringMatch(str string, pattern string) bool {
 		}
 
 		if lastStarIdx != -1 {
+			backtracks++
+			if maxBacktrack > 0 && backtracks > maxBacktrack {
+				return false
+			}
 			j = lastStarIdx + 1
 			lastStrIdx++
 			i = lastStrIdx
@@ -255,79 +373,547 @@ func stringMatch(str string, pattern string) bool {
 // Tries to match the path components against the rule components
 // matches is true if the path matches the rule, final is true if the rule matched the whole path
 // the final parameter is used for rules that match directories only
-func matchComponents(path []string, components []string) (matches bool, final bool) {
+// anchor is the index of the first path component matched by a component other than a leading "**"
+// isDir and options are used to decide whether a component should be case folded
+func matchComponents(path []string, components []string, isDir bool, options *Options) (matches bool, final bool, anchor int) {
 	i := 0
 	for ; i < len(components); i++ {
 		if i >= len(path) {
 			// we ran out of path components, but still have components to match
-			return false, false
+			return false, false, 0
 		}
 		if components[i] == "**" {
+			if i == len(components)-1 {
+				// a trailing "**" matches everything inside, so it has to consume at least one component
+				// if there is more than one left, the first one is a directory that the rest is inside of
+				return true, len(path)-i == 1, 0
+			}
 			// stinky recursive step
 			for j := len(path) - 1; j >= i; j-- {
-				match, final := matchComponents(path[j:], components[i+1:])
+				match, final, subAnchor := matchComponents(path[j:], components[i+1:], isDir, options)
 				if match {
-					// pass final trough
-					return true, final
+					// pass final trough, the anchor only moves if the "**" was leading
+					if i == 0 {
+						return true, final, j + subAnchor
+					}
+					return true, final, 0
 				}
 			}
-			return false, false
+			return false, false, 0
 		}
 
-		if !stringMatch(path[i], components[i]) {
-			return false, false
+		if !stringMatchFold(path[i], components[i], options.foldComponent(i == len(path)-1 && !isDir), options.MaxWildcardBacktrack) {
+			return false, false, 0
 		}
 	}
-	return true, i == len(path) // if we matched all components, check if we are at the end of the path
+	return true, i == len(path), 0 // if we matched all components, check if we are at the end of the path
 }
 
 // Tries to match the path against the rule
 // the function expects a buffer of sufficient size to get passed to it, this avoids excessive memory allocation
-func (r *Rule) matchesPath(isDirectory bool, pathComponents []string) bool {
+func (r *Rule) matchesPath(isDirectory bool, pathComponents []string, options *Options) bool {
+	match, _ := r.matchesPathAt(isDirectory, pathComponents, options)
+	return match
+}
+
+// Same as matchesPath, but also returns the index of the path component the match was anchored at
+func (r *Rule) matchesPathAt(isDirectory bool, pathComponents []string, options *Options) (bool, int) {
+	// isDirectory decides the case folding, couldBeDir decides if directory-only rules can match
+	couldBeDir := options.couldBeDir(isDirectory)
+	if r.regex != nil {
+		return r.matchesRegex(couldBeDir, pathComponents), 0
+	}
+	if r.exact && !options.FoldDirectories && !options.FoldFiles {
+		// fast path, compare the components directly
+		for j := 0; j < len(pathComponents); j++ {
+			if pathComponents[j] == r.Components[0] {
+				final := j == len(pathComponents)-1
+				return !r.OnlyDirectory || r.OnlyDirectory && (!final || final && couldBeDir), j
+			}
+		}
+
+		return false, 0
+	}
+
 	if !r.Relative {
 		// stinky recursive step
 		for j := 0; j < len(pathComponents); j++ {
-			match, final := matchComponents(pathComponents[j:], r.Components)
+			match, final, anchor := matchComponents(pathComponents[j:], r.Components, isDirectory, options)
 			if match {
-				return !r.OnlyDirectory || r.OnlyDirectory && (!final || final && isDirectory)
+				return !r.OnlyDirectory || r.OnlyDirectory && (!final || final && couldBeDir), j + anchor
+			}
+		}
+
+		return false, 0
+	}
+
+	match, final, anchor := matchComponents(pathComponents, r.Components, isDirectory, options)
+
+	return match && (!r.OnlyDirectory || r.OnlyDirectory && (!final || final && couldBeDir)), anchor
+}
+
+// Tries to match the whole path against the rule components, unlike matchComponents it doesn't match descendants
+// a trailing "**" has to match at least one component, other "**" components can match none
+func matchComponentsExact(path []string, components []string, isDir bool, options *Options) bool {
+	for i := 0; i < len(components); i++ {
+		if components[i] == "**" {
+			if i == len(components)-1 {
+				return len(path) > i
+			}
+			// stinky recursive step
+			for j := i; j <= len(path); j++ {
+				if matchComponentsExact(path[j:], components[i+1:], isDir, options) {
+					return true
+				}
 			}
+			return false
+		}
+
+		if i >= len(path) || !stringMatchFold(path[i], components[i], options.foldComponent(i == len(path)-1 && !isDir), options.MaxWildcardBacktrack) {
+			return false
 		}
+	}
+	return len(path) == len(components)
+}
 
+// matches the regex of the rule against the whole path, directories get a trailing '/'
+func (r *Rule) matchesRegex(isDirectory bool, pathComponents []string) bool {
+	path := strings.Join(pathComponents, "/")
+	if isDirectory {
+		path += "/"
+	}
+	return r.regex.MatchString(path)
+}
+
+// Tries to match a single entry against the rule, the way git does, without matching descendants of the entry
+func (r *Rule) matchesEntryExact(isDirectory bool, pathComponents []string, options *Options) bool {
+	couldBeDir := options.couldBeDir(isDirectory)
+	if r.regex != nil {
+		return r.matchesRegex(couldBeDir, pathComponents)
+	}
+	if r.OnlyDirectory && !couldBeDir {
 		return false
 	}
+	if r.Relative {
+		return matchComponentsExact(pathComponents, r.Components, isDirectory, options)
+	}
 
-	match, final := matchComponents(pathComponents, r.Components)
+	for j := 0; j < len(pathComponents); j++ {
+		if matchComponentsExact(pathComponents[j:], r.Components, isDirectory, options) {
+			return true
+		}
+	}
+	return false
+}
+
+// Options controls optional, non-git behaviour of the matcher
+// FoldDirectories makes matching of directory components case-insensitive
+// FoldFiles makes matching of the final file component case-insensitive
+// folding uses Unicode simple case folding, so it is the same in every locale, and "İ" does not match "i"
+// a path component counts as a directory if it is not the last one, or if the path ends with a '/'
+// NormalizeUnicode applies NFC normalization to patterns and paths, so NFD paths (e.g. on macOS) match NFC patterns
+// WindowsPaths removes the volume from paths with StripVolume, and treats '\\' as a path separator on every platform
+// CommentPrefix is the prefix of comment lines, "#" if empty, a leading '\\' escapes it like in git
+//
+// the limits below guard against untrusted input, zero means no limit
+// lines over a limit are skipped, unless Strict is set and the rules are compiled with CompileIgnoreReader or CompileIgnoreLinesChecked,
+// which return an error, the other constructors can't report errors, so they skip the rejected lines even with Strict
+// MaxLineLength is the maximum length of a line in bytes, including leading and trailing whitespace
+// MaxRules is the maximum number of rules, blank lines and comments don't count
+// MaxPatternComponents is the maximum number of path components of a single pattern
+// Strict also rejects lines that ValidatePattern reports as invalid
+//
+// by default runs of '/' are collapsed in patterns and paths, so "a//b" and "a/b" are the same
+// ExactSlashes disables this like in git, where a//b never matches anything, and paths containing "//" are never matched
+//
+// paths are cleaned before matching, so "a/../b" is matched as "b", paths that leave the root like "../x" never match
+// RejectDotDot makes paths with any ".." component never match, instead of cleaning them
+//
+// RegexMode treats every pattern as a Go regexp, that is matched against the whole cleaned path, with a trailing '/' for directories
+// a leading '!' still negates the rule, and comments, blank lines and the order of the rules work the same way,
+// but the anchoring, directory-only and case folding rules of patterns don't apply, lines that are not valid regexps are skipped
+// WalkMatcher and CanSkipDir can't assume that a rule matching a directory matches its contents, so they don't cache or skip anything
+//
+// MaxWildcardBacktrack limits how many times matching a single path component can backtrack to a '*', zero means no limit
+// a component that needs more backtracking is treated as not matching, this bounds the time spent on untrusted patterns
+//
+// by default a path is only a directory if it ends with a '/', or if MatchesEntry is called with isDir
+// SlashlessDirs treats every path as possibly a directory, so "foo/" also matches "foo", this diverges from git,
+// and is meant for callers that strip trailing slashes and have no way to tell directories apart,
+// a final component without a trailing '/' is then case folded if either FoldFiles or FoldDirectories is set
+type Options struct {
+	FoldDirectories      bool
+	FoldFiles            bool
+	NormalizeUnicode     bool
+	WindowsPaths         bool
+	CommentPrefix        string
+	MaxLineLength        int
+	MaxRules             int
+	MaxPatternComponents int
+	Strict               bool
+	ExactSlashes         bool
+	RejectDotDot         bool
+	SlashlessDirs        bool
+	MaxWildcardBacktrack int
+	RegexMode            bool
+}
+
+// ErrLimitExceeded is returned by CompileIgnoreReader in strict mode, if a line is over one of the limits in Options
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// reports whether the trimmed line is a comment
+func (o *Options) isComment(line string) bool {
+	if o.CommentPrefix == "" {
+		return isDefaultComment(line)
+	}
+	return strings.HasPrefix(line, o.CommentPrefix)
+}
+
+// reports whether a component should be case folded
+// with SlashlessDirs a final component that is not known to be a directory could be either, so either option folds it
+func (o *Options) foldComponent(isFile bool) bool {
+	if isFile {
+		return o.FoldFiles || o.SlashlessDirs && o.FoldDirectories
+	}
+	return o.FoldDirectories
+}
 
-	return match && (!r.OnlyDirectory || r.OnlyDirectory && (!final || final && isDirectory))
+// reports whether directory-only rules can match a path, SlashlessDirs treats every path as possibly a directory
+func (o *Options) couldBeDir(isDir bool) bool {
+	return isDir || o.SlashlessDirs
 }
 
 // Stores a list of rules for matching paths against .gitignore patterns
+// a GitIgnore without rules, like one compiled from an empty file, matches nothing
 // PathComponentsBuf is a temporary buffer for mySplit calls, this avoids excessive allocation
+// DirectoriesOnly is true for matchers returned by DirMatcher, that treat every path as a directory
+// LineCount and Layer give the Line and Layer of added rules, lines continue after the ones compiled so far
 type GitIgnore struct {
 	Rules             []Rule
+	options           Options
+	directoriesOnly   bool
+	lineCount         int
+	layer             int
 	pathComponentsBuf []string
 }
 
 // Creates a Gitignore from a list of patterns (lines in a .gitignore file)
 func CompileIgnoreLines(patterns []string) *GitIgnore {
+	return CompileIgnoreLinesWithOptions(patterns, Options{})
+}
+
+// Same as CompileIgnoreLines, but with custom options
+func CompileIgnoreLinesWithOptions(patterns []string, options Options) *GitIgnore {
 	gitignore := &GitIgnore{
 		Rules:             make([]Rule, 0, len(patterns)),
+		options:           options,
 		pathComponentsBuf: make([]string, 2048),
 	}
 
 	for _, pattern := range patterns {
-		// skip empty lines, comments, and trailing/leading whitespace
-		pattern = strings.Trim(pattern, " \t\r\n")
-		if pattern == "" || pattern == "!" || pattern[0] == '#' {
+		// lines over the limits are skipped
+		_ = gitignore.addLine(pattern)
+	}
+
+	return gitignore
+}
+
+// Same as CompileIgnoreLinesWithOptions, but if Strict is set, returns the first line that is invalid or over a limit
+func CompileIgnoreLinesChecked(patterns []string, options Options) (*GitIgnore, error) {
+	gitignore := CompileIgnoreLinesWithOptions(nil, options)
+
+	for _, pattern := range patterns {
+		if err := gitignore.addLine(pattern); err != nil && options.Strict {
+			return nil, err
+		}
+	}
+
+	return gitignore, nil
+}
+
+// Same as CompileIgnoreLinesWithOptions, but reads the lines from r
+// lines longer than MaxLineLength are never held in memory completely
+// returns the error of r, or if Strict is set, the first line that is invalid or over a limit
+func CompileIgnoreReader(r io.Reader, options Options) (*GitIgnore, error) {
+	gitignore := CompileIgnoreLinesWithOptions(nil, options)
+
+	reader := bufio.NewReader(r)
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// ReadLine only drops the '\r' of a CRLF line ending if the '\n' follows it, leave room for one,
+		// addLine checks the exact length
+		maxLength := options.MaxLineLength + 1
+		tooLong := options.MaxLineLength > 0 && len(line)+len(chunk) > maxLength
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		// consume the rest of the line
+		for isPrefix {
+			chunk, isPrefix, err = reader.ReadLine()
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			tooLong = tooLong || options.MaxLineLength > 0 && len(line)+len(chunk) > maxLength
+			if !tooLong {
+				line = append(line, chunk...)
+			}
+		}
+
+		if tooLong {
+			gitignore.lineCount++
+			err = fmt.Errorf("line %d: %w: longer than %d bytes", gitignore.lineCount, ErrLimitExceeded, options.MaxLineLength)
+		} else {
+			err = gitignore.addLine(string(line))
+		}
+		if err != nil && options.Strict {
+			return nil, err
+		}
+		line = line[:0]
+	}
+
+	return gitignore, nil
+}
+
+// compiles a line and appends its rule, the line is numbered after the previous ones
+// returns an error if the line is over a limit, or if Strict is set and the line is invalid, the rule is not added then
+func (g *GitIgnore) addLine(line string) error {
+	g.lineCount++
+
+	rule, ok, err := g.compileLineChecked(line, g.lineCount)
+	if err != nil || !ok {
+		return err
+	}
+	if err := g.checkRuleCount(g.lineCount); err != nil {
+		return err
+	}
+	rule.Line = g.lineCount
+	rule.Layer = g.layer
+
+	g.Rules = append(g.Rules, rule)
+	return nil
+}
+
+// same as compileLine, but checks the limits of the options, and validates the line if Strict is set
+// lineNumber is only used in the errors
+func (g *GitIgnore) compileLineChecked(line string, lineNumber int) (rule Rule, ok bool, err error) {
+	options := &g.options
+
+	// the '\r' of a CRLF line ending is not part of the line
+	if options.MaxLineLength > 0 && len(strings.TrimSuffix(line, "\r")) > options.MaxLineLength {
+		return Rule{}, false, fmt.Errorf("line %d: %w: longer than %d bytes", lineNumber, ErrLimitExceeded, options.MaxLineLength)
+	}
+	if options.Strict {
+		validate := validatePattern
+		if options.RegexMode {
+			validate = validateRegexLine
+		}
+		if err := validate(line, options.isComment); err != nil {
+			return Rule{}, false, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+
+	rule, ok = compileLine(line, options)
+	if !ok {
+		return Rule{}, false, nil
+	}
+	if options.MaxPatternComponents > 0 && len(rule.Components) > options.MaxPatternComponents {
+		return Rule{}, false, fmt.Errorf("line %d: %w: more than %d path components", lineNumber, ErrLimitExceeded, options.MaxPatternComponents)
+	}
+	return rule, true, nil
+}
+
+// returns an error if one more rule would be over MaxRules
+func (g *GitIgnore) checkRuleCount(lineNumber int) error {
+	if g.options.MaxRules > 0 && len(g.Rules) >= g.options.MaxRules {
+		return fmt.Errorf("line %d: %w: more than %d rules", lineNumber, ErrLimitExceeded, g.options.MaxRules)
+	}
+	return nil
+}
+
+// Same as CompileIgnoreFile, but reads multiple files, rules in later files take precedence
+func CompileIgnoreFiles(filenames ...string) (*GitIgnore, error) {
+	ignores := make([]*GitIgnore, 0, len(filenames))
+	for _, filename := range filenames {
+		ignore, err := CompileIgnoreFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		ignores = append(ignores, ignore)
+	}
+	return Merge(ignores...), nil
+}
+
+// Merges multiple gitignores into one, rules in later gitignores take precedence
+// the rules keep their Source and Line, the options of the first gitignore are used
+// the Layer of each rule is the index of the gitignore it came from,
+// layers of gitignores that were merged before are kept, and the following ones are shifted after them
+// patterns added to the result later are numbered from line 1, in the layer after the last source
+func Merge(ignores ...*GitIgnore) *GitIgnore {
+	merged := CompileIgnoreLines(nil)
+	if len(ignores) > 0 {
+		merged.options = ignores[0].options
+	}
+
+	layer := 0
+	for _, ignore := range ignores {
+		layers := 1
+		for _, rule := range ignore.Rules {
+			layers = max(layers, rule.Layer+1)
+			rule.Layer += layer
+			merged.Rules = append(merged.Rules, rule)
+		}
+		layer += layers
+	}
+	merged.layer = layer
+	return merged
+}
+
+// Compiles the ignore rules for a directory, paths passed to the result are relative to dir
+// reads dir/.gitignore and, if dir is the root of a repository, the info/exclude file of the repository
+// rules from .gitignore take precedence over the rules in info/exclude, missing files are skipped
+func CompileDir(dir string) (*GitIgnore, error) {
+	exclude, err := infoExcludeFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	filenames := []string{filepath.Join(dir, ".gitignore")}
+	if exclude != "" {
+		filenames = []string{exclude, filenames[0]}
+	}
+
+	ignores := make([]*GitIgnore, 0, len(filenames))
+	for _, filename := range filenames {
+		ignore, err := CompileIgnoreFile(filename)
+		if errors.Is(err, fs.ErrNotExist) {
 			continue
 		}
+		if err != nil {
+			return nil, err
+		}
+		ignores = append(ignores, ignore)
+	}
 
-		rule := createRule(pattern)
+	return Merge(ignores...), nil
+}
 
-		gitignore.Rules = append(gitignore.Rules, rule)
+// Returns the path of the info/exclude file of the repository in dir, or "" if dir is not the root of a repository
+func infoExcludeFile(dir string) (string, error) {
+	gitDir, err := commonGitDir(dir)
+	if gitDir == "" || err != nil {
+		return "", err
 	}
+	return filepath.Join(gitDir, "info", "exclude"), nil
+}
 
-	return gitignore
+// Returns the git directory of the repository in dir, or "" if dir is not the root of a repository
+// .git is either the git directory, or for worktrees and submodules a file with a "gitdir: " line pointing to it,
+// worktrees share the info/exclude and config of the main repository, which their commondir file points to
+func commonGitDir(dir string) (string, error) {
+	gitDir := filepath.Join(dir, ".git")
+	info, err := os.Stat(gitDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		content, err := os.ReadFile(gitDir)
+		if err != nil {
+			return "", err
+		}
+		target, found := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+		if !found {
+			// not a repository
+			return "", nil
+		}
+		gitDir = resolvePath(dir, strings.TrimSpace(target))
+	}
+
+	if content, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		gitDir = resolvePath(gitDir, strings.TrimSpace(string(content)))
+	}
+	return gitDir, nil
+}
+
+// resolves a path read from a git file, relative paths are relative to dir
+func resolvePath(dir string, path string) string {
+	path = filepath.FromSlash(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// removes the line ending and the leading and trailing whitespace of a line
+// a trailing space or tab escaped with a '\' is kept, like in "foo\ "
+func trimLine(line string) string {
+	line = strings.TrimLeft(strings.TrimRight(line, "\r\n"), " \t")
+	for len(line) > 0 && (line[len(line)-1] == ' ' || line[len(line)-1] == '\t') {
+		backslashes := 0
+		for i := len(line) - 2; i >= 0 && line[i] == '\\'; i-- {
+			backslashes++
+		}
+		if backslashes%2 == 1 {
+			break
+		}
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// creates a rule from a single line of a .gitignore file
+// ok is false if the line does not contain a rule
+func compileLine(line string, options *Options) (rule Rule, ok bool) {
+	// skip empty lines, comments, and trailing/leading whitespace
+	pattern := trimLine(line)
+	if pattern == "" || pattern == "!" || options.isComment(pattern) {
+		return Rule{}, false
+	}
+	if options.NormalizeUnicode {
+		pattern = norm.NFC.String(pattern)
+	}
+
+	if options.RegexMode {
+		rule, ok = createRegexRule(pattern)
+	} else {
+		rule, ok = createRule(pattern, options), true
+	}
+	rule.Pattern = pattern
+	return rule, ok
+}
+
+// creates a rule from a regexp, ok is false if it doesn't compile
+func createRegexRule(pattern string) (rule Rule, ok bool) {
+	if pattern[0] == '!' {
+		rule.Negate = true
+		pattern = pattern[1:]
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, false
+	}
+	rule.regex = regex
+	return rule, true
+}
+
+// checks a single line in RegexMode, blank lines and the lines isComment reports are valid
+func validateRegexLine(line string, isComment func(string) bool) error {
+	pattern := trimLine(line)
+	if pattern == "" || isComment(pattern) {
+		return nil
+	}
+	_, err := regexp.Compile(strings.TrimPrefix(pattern, "!"))
+	return err
 }
 
 // Same as CompileIgnoreLines, but reads from a file
@@ -337,11 +923,20 @@ func CompileIgnoreFile(filename string) (*GitIgnore, error) {
 	if err != nil {
 		return nil, err
 	}
-	return CompileIgnoreLines(strings.Split(string(lines), "\n")), nil
+	patterns := strings.Split(string(lines), "\n")
+	// a trailing newline ends the last line, it doesn't start a new one
+	if patterns[len(patterns)-1] == "" {
+		patterns = patterns[:len(patterns)-1]
+	}
+	gitignore := CompileIgnoreLines(patterns)
+	for i := range gitignore.Rules {
+		gitignore.Rules[i].Source = filename
+	}
+	return gitignore, nil
 }
 
 // create a rule from a pattern
-func createRule(pattern string) Rule {
+func createRule(pattern string, options *Options) Rule {
 	negate := false
 	onlyDirectory := false
 	relative := false
@@ -364,19 +959,309 @@ func createRule(pattern string) Rule {
 	// we use the default split function because this only runs once for each rule
 	// this saves memory compared to using mySplit
 	components := mySplit(pattern, '/')
+	if options.ExactSlashes {
+		// keep the empty components, so the rule can't match paths without them
+		components = strings.Split(strings.TrimSuffix(pattern, "/"), "/")
+	}
+
+	// a "." component doesn't change the path the pattern refers to, so "./build" is the same as "build"
+	// git never matches such patterns, but they are almost always meant this way
+	if len(components) > 1 {
+		kept := components[:0]
+		for _, component := range components {
+			if component != "." {
+				kept = append(kept, component)
+			}
+		}
+		if len(kept) > 0 {
+			components = kept
+		}
+	}
 
-	return Rule{
-		Components:    components,
-		Negate:        negate,
-		OnlyDirectory: onlyDirectory,
-		Relative:      relative || len(components) > 1,
+	// a leading "**" matches at any depth, which is what a rule that isn't relative does anyway
+	// dropping it lets the rule use the cheaper matching of floating rules
+	floating := false
+	for len(components) > 1 && components[0] == "**" {
+		components = components[1:]
+		floating = true
 	}
+
+	rule := Rule{
+		Components:        components,
+		Negate:            negate,
+		OnlyDirectory:     onlyDirectory,
+		Relative:          !floating && (relative || len(components) > 1),
+		leadingDoubleStar: floating,
+	}
+	rule.exact = !rule.Relative && len(components) == 1 && !strings.ContainsAny(components[0], "*?[\\")
+	return rule
 }
 
-// Tries to match the path to all the rules in the gitignore
-func (g *GitIgnore) MatchesPath(path string) bool {
+// Describes a single rule, for displaying it to users
+// Anchored is true if the rule only matches relative to the root of the gitignore
+// Literal is true if the pattern contains no wildcards
+type RuleInfo struct {
+	Pattern  string
+	Line     int
+	Source   string
+	Layer    int
+	Negated  bool
+	DirOnly  bool
+	Anchored bool
+	Literal  bool
+}
+
+// Returns a description of every rule, in order of precedence (lowest first)
+func (g *GitIgnore) Describe() []RuleInfo {
+	infos := make([]RuleInfo, len(g.Rules))
+	for i := range g.Rules {
+		rule := &g.Rules[i]
+		infos[i] = RuleInfo{
+			Pattern:  rule.Pattern,
+			Line:     rule.Line,
+			Source:   rule.Source,
+			Layer:    rule.Layer,
+			Negated:  rule.Negate,
+			DirOnly:  rule.OnlyDirectory,
+			Anchored: rule.Relative,
+			Literal:  rule.isLiteral(),
+		}
+	}
+	return infos
+}
+
+// Returns a hash of the compiled rules, in order, so callers can detect when a ruleset changes
+// rules compiled to the same components hash the same, so "./build" hashes like "build"
+// the line numbers and sources of the rules don't change the hash, the hash is stable across runs
+// the options that change how paths are matched, like FoldFiles or MaxWildcardBacktrack, are part of the hash
+func (g *GitIgnore) Hash() uint64 {
+	h := fnv.New64a()
+	options := &g.options
+	var optionFlags uint16
+	for i, set := range []bool{
+		g.directoriesOnly, options.FoldFiles, options.FoldDirectories, options.NormalizeUnicode, options.WindowsPaths,
+		options.ExactSlashes, options.SlashlessDirs, options.RejectDotDot, options.RegexMode,
+	} {
+		if set {
+			optionFlags |= 1 << i
+		}
+	}
+	// the default options write nothing, so they keep the hashes of plain rulesets unchanged
+	if optionFlags != 0 || options.MaxWildcardBacktrack != 0 {
+		header := binary.LittleEndian.AppendUint16(nil, optionFlags)
+		h.Write(binary.AppendVarint(header, int64(options.MaxWildcardBacktrack)))
+	}
+	for i := range g.Rules {
+		rule := &g.Rules[i]
+		var flags byte
+		if rule.Negate {
+			flags |= 1
+		}
+		if rule.OnlyDirectory {
+			flags |= 2
+		}
+		if rule.Relative {
+			flags |= 4
+		}
+		h.Write([]byte{flags})
+		if rule.regex != nil {
+			h.Write([]byte(rule.regex.String()))
+		}
+		for _, component := range rule.Components {
+			h.Write([]byte(component))
+			h.Write([]byte{'/'})
+		}
+		// separates the rules
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// Returns a matcher for directory queries, useful for deciding if a walk can skip a directory
+// the returned matcher is a view of the same rules in which every path is a directory, even without a trailing '/'
+// the rules are copied unchanged, so Describe, Hash and ToGlobs still see the original patterns
+func (g *GitIgnore) DirMatcher() *GitIgnore {
+	dirMatcher := g.Clone()
+	dirMatcher.directoriesOnly = true
+	return dirMatcher
+}
+
+// Returns a copy of the gitignore, that can be modified without affecting the original
+func (g *GitIgnore) Clone() *GitIgnore {
+	clone := *g
+	clone.Rules = append([]Rule(nil), g.Rules...)
+	clone.pathComponentsBuf = make([]string, 2048)
+	return &clone
+}
+
+// Returns the paths whose ignore status would change if newPattern was appended to the rules
+// the gitignore itself is not modified
+func (g *GitIgnore) WouldChange(newPattern string, paths []string) (changed []string) {
+	// a pattern that AddPatterns would skip can't change anything
+	rule, ok, err := g.compileLineChecked(newPattern, g.lineCount+1)
+	if err != nil || !ok || g.checkRuleCount(g.lineCount+1) != nil {
+		return nil
+	}
+	clone := g.Clone()
+	clone.Rules = append(clone.Rules, rule)
+
+	for _, path := range paths {
+		if g.MatchesPath(path) != clone.MatchesPath(path) {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+// Appends patterns to the gitignore, they take precedence over the existing rules
+// the patterns are numbered as if they were lines following the ones the gitignore was compiled from,
+// on a merged gitignore they are numbered from line 1 in a new layer, after the layers of the sources
+// the limits of the options apply, patterns over them are skipped
+func (g *GitIgnore) AddPatterns(patterns ...string) {
+	for _, pattern := range patterns {
+		_ = g.addLine(pattern)
+	}
+}
+
+// Same as MatchesEntry, but evaluates the path directory by directory, like git does
+// a path inside an ignored directory is always ignored, even if a negation would re-include it,
+// and rules only match the entry itself, not its descendants
+// the results are the same as `git check-ignore` reports
+func (g *GitIgnore) MatchesPathGit(path string, isDir bool) bool {
+	pathComponents, hasSlash, ok := g.splitPath(path)
+	if !ok {
+		return false
+	}
+	return g.matchWalk(pathComponents, isDir || hasSlash)
+}
+
+// evaluates every leading directory of the path, and then the path itself
+func (g *GitIgnore) matchWalk(components []string, isDir bool) bool {
+	for k := 1; k <= len(components); k++ {
+		entryIsDir := k < len(components) || isDir
+
+		_, ignored := g.decideEntry(components[:k], entryIsDir)
+
+		// a file inside an ignored directory can't be re-included
+		if ignored || k == len(components) {
+			return ignored
+		}
+	}
+	return false
+}
+
+// decides a single entry the way git does, without looking at its parent directories
+// matched is false if no rule matches the entry
+func (g *GitIgnore) decideEntry(components []string, isDir bool) (matched bool, ignored bool) {
+	for i := len(g.Rules) - 1; i >= 0; i-- {
+		if g.Rules[i].matchesEntryExact(isDir, components, &g.options) {
+			return true, !g.Rules[i].Negate
+		}
+	}
+	return false, false
+}
+
+// Same as MatchesPath, but the extra patterns are evaluated after the rules, as if they were appended to them
+// the extra patterns are compiled on every call, use AddPatterns if they are used repeatedly
+func (g *GitIgnore) MatchesPathWith(path string, extra []string) bool {
+	pathComponents, isDir, ok := g.splitPath(path)
+	if !ok {
+		return false
+	}
+
+	deciding := g.decidingRule(pathComponents, isDir)
+	matched := deciding != -1 && !g.Rules[deciding].Negate
+	rules := len(g.Rules)
+	for i, pattern := range extra {
+		// patterns that AddPatterns would skip are skipped here too
+		rule, ok, err := g.compileLineChecked(pattern, g.lineCount+i+1)
+		if err != nil || !ok || g.options.MaxRules > 0 && rules >= g.options.MaxRules {
+			continue
+		}
+		rules++
+		if rule.matchesPath(isDir, pathComponents, &g.options) {
+			matched = !rule.Negate
+		}
+	}
+	return matched
+}
+
+// Replaces the line at index (0-based, in the patterns the gitignore was compiled from) with a new pattern
+// only the rule of that line is recompiled, the order of the other rules is left untouched
+// if the new line is blank or a comment, the rule of the old line is removed
+// the limits and Strict apply like when compiling, the rules are left untouched if the new line is rejected
+// the lines of merged gitignores can't be replaced, only the ones added to the result with AddPatterns
+func (g *GitIgnore) ReplaceLine(index int, newPattern string) error {
+	if index < 0 || index >= g.lineCount {
+		return fmt.Errorf("line index %d out of range [0, %d)", index, g.lineCount)
+	}
+	line := index + 1
+
+	// the rules of the current layer come after the ones of merged sources
+	start := len(g.Rules)
+	for start > 0 && g.Rules[start-1].Layer == g.layer {
+		start--
+	}
+
+	// find the rule of the line, or the position where it would be inserted
+	pos := start
+	for pos < len(g.Rules) && g.Rules[pos].Line < line {
+		pos++
+	}
+	exists := pos < len(g.Rules) && g.Rules[pos].Line == line
+
+	rule, ok, err := g.compileLineChecked(newPattern, line)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if exists {
+			g.Rules = append(g.Rules[:pos], g.Rules[pos+1:]...)
+		}
+		return nil
+	}
+	if !exists {
+		if err := g.checkRuleCount(line); err != nil {
+			return err
+		}
+	}
+	rule.Line = line
+	rule.Layer = g.layer
+
+	if exists {
+		rule.Source = g.Rules[pos].Source
+		g.Rules[pos] = rule
+		return nil
+	}
+
+	if pos > start {
+		rule.Source = g.Rules[pos-1].Source
+	} else if pos < len(g.Rules) {
+		rule.Source = g.Rules[pos].Source
+	}
+	g.Rules = append(g.Rules, Rule{})
+	copy(g.Rules[pos+1:], g.Rules[pos:])
+	g.Rules[pos] = rule
+	return nil
+}
+
+// Cleans the path, and reports whether it refers to a directory
+// ok is false if the path can never match any rule
+func (g *GitIgnore) cleanPath(path string) (cleaned string, isDir bool, ok bool) {
+	if g.options.WindowsPaths {
+		path = strings.ReplaceAll(StripVolume(path), "\\", "/")
+	}
+	if g.options.ExactSlashes && strings.Contains(path, "//") {
+		return "", false, false
+	}
+	if g.options.RejectDotDot && hasDotDot(filepath.ToSlash(path)) {
+		return "", false, false
+	}
 	// TODO: check if path actually points to a directory on the filesystem
-	isDir := strings.HasSuffix(path, "/")
+	isDir = g.directoriesOnly || strings.HasSuffix(path, "/")
+	if g.options.NormalizeUnicode {
+		path = norm.NFC.String(path)
+	}
 	path = filepath.Clean(path)
 	path = filepath.ToSlash(path)
 	if path == "." {
@@ -384,22 +1269,236 @@ func (g *GitIgnore) MatchesPath(path string) bool {
 		isDir = true
 	}
 	if path == "*" {
-		return false
+		return "", false, false
 	}
 	if !fs.ValidPath(path) {
+		return "", false, false
+	}
+	return path, isDir, true
+}
+
+// reports whether the slash separated path has a ".." component
+func hasDotDot(path string) bool {
+	return path == ".." || strings.HasPrefix(path, "../") || strings.HasSuffix(path, "/..") || strings.Contains(path, "/../")
+}
+
+// Splits the path into components, and reports whether it refers to a directory
+// ok is false if the path can never match any rule
+func (g *GitIgnore) splitPath(path string) (pathComponents []string, isDir bool, ok bool) {
+	return g.splitPathBuf(path, g.pathComponentsBuf)
+}
+
+// Same as splitPath, but splits into the given buffer, so it can be used concurrently
+func (g *GitIgnore) splitPathBuf(path string, buf []string) (pathComponents []string, isDir bool, ok bool) {
+	path, isDir, ok = g.cleanPath(path)
+	if !ok {
+		return nil, false, false
+	}
+	return mySplitBuf(path, '/', buf), isDir, true
+}
+
+// Returns the index of the last rule that matches the path, which decides if the path is ignored
+// returns -1 if no rule matches
+func (g *GitIgnore) decidingRule(pathComponents []string, isDir bool) int {
+	// the last matching rule decides, so the first match from the end is the one
+	for i := len(g.Rules) - 1; i >= 0; i-- {
+		if g.Rules[i].matchesPath(isDir, pathComponents, &g.options) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ErrInvalidPath is returned by MatchesPathErr for paths that can never be matched, like absolute paths or "../x"
+var ErrInvalidPath = errors.New("invalid path")
+
+// Tries to match the path to all the rules in the gitignore
+// the path is cleaned first, absolute paths and paths leaving the root (like "../x") never match
+func (g *GitIgnore) MatchesPath(path string) bool {
+	return g.MatchesEntry(path, false)
+}
+
+// Same as MatchesPath, but returns an error wrapping ErrInvalidPath if the path can never be matched
+func (g *GitIgnore) MatchesPathErr(path string) (bool, error) {
+	if _, _, ok := g.cleanPath(path); !ok {
+		return false, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+	}
+	return g.MatchesPath(path), nil
+}
+
+// Same as MatchesPath, but the caller tells if the path is a directory, so it doesn't need a trailing '/'
+// a trailing '/' still marks the path as a directory
+func (g *GitIgnore) MatchesEntry(path string, isDir bool) bool {
+	pathComponents, hasSlash, ok := g.splitPath(path)
+	if !ok {
 		return false
 	}
-	pathComponents := mySplitBuf(path, '/', g.pathComponentsBuf)
-	matched := false
 
-	for _, rule := range g.Rules {
-		if rule.matchesPath(isDir, pathComponents) {
-			if !rule.Negate {
-				matched = true
-			} else {
-				matched = false
-			}
+	deciding := g.decidingRule(pathComponents, isDir || hasSlash)
+	return deciding != -1 && !g.Rules[deciding].Negate
+}
+
+// Matches the path relative to base, which is the directory the rules are for
+// base and path can both be absolute, or both relative to the same directory
+// inScope is false if path is not inside base, base itself is in scope, but never ignored
+func (g *GitIgnore) MatchesRelativeTo(base string, path string) (ignored bool, inScope bool) {
+	if g.options.WindowsPaths {
+		base = strings.ReplaceAll(base, "\\", "/")
+		path = strings.ReplaceAll(path, "\\", "/")
+	}
+	isDir := strings.HasSuffix(path, "/")
+	base = filepath.ToSlash(filepath.Clean(base))
+	path = filepath.ToSlash(filepath.Clean(path))
+
+	if path == base {
+		return false, true
+	}
+	rel := path
+	if base != "." {
+		prefix := strings.TrimSuffix(base, "/") + "/"
+		var found bool
+		if rel, found = strings.CutPrefix(path, prefix); !found {
+			return false, false
 		}
+	} else if path == ".." || strings.HasPrefix(path, "../") || strings.HasPrefix(path, "/") {
+		return false, false
 	}
-	return matched
+	return g.MatchesEntry(rel, isDir), true
+}
+
+// EntryKind is the type of a filesystem entry, used by MatchesEntryKind
+type EntryKind int
+
+const (
+	// File is a regular file, or anything else that is not a directory or a symlink
+	File EntryKind = iota
+	// Dir is a directory, directory-only rules match it
+	Dir
+	// Symlink is a symbolic link, git does not follow them, so they are matched like files
+	Symlink
+)
+
+// Same as MatchesEntry, but takes the kind of the entry
+// symlinks are matched like files, so directory-only rules don't match a symlink even if it points to a directory
+func (g *GitIgnore) MatchesEntryKind(path string, kind EntryKind) bool {
+	// git doesn't follow symlinks, so only Dir is a directory
+	return g.MatchesEntry(path, kind == Dir)
+}
+
+// Same as MatchesPath, but also returns the rule that decided the result
+// rule is nil if no rule matched, if the deciding rule is a negation, ignored is false
+// the Source and Line of the rule tell where the pattern came from
+func (g *GitIgnore) MatchesPathHow(path string) (ignored bool, rule *Rule) {
+	pathComponents, isDir, ok := g.splitPath(path)
+	if !ok {
+		return false, nil
+	}
+
+	deciding := g.decidingRule(pathComponents, isDir)
+	if deciding == -1 {
+		return false, nil
+	}
+	return !g.Rules[deciding].Negate, &g.Rules[deciding]
+}
+
+// Reports whether the path is ignored by a rule with a wildcard ('*', '?', '[' or "**"), instead of a literal one
+// useful for warning about paths that are only ignored by a broad pattern
+func (g *GitIgnore) MatchedByWildcard(path string) bool {
+	ignored, rule := g.MatchesPathHow(path)
+	if !ignored {
+		return false
+	}
+	return !rule.isLiteral()
+}
+
+// Same as MatchesPath, but also returns the index of the path component where the deciding rule matched
+// for a rule like "**/foo" this is the component matched by "foo", not the start of the path
+// matchedAt is -1 if no rule matched, if the deciding rule is a negation, ignored is false
+func (g *GitIgnore) MatchDetail(path string) (ignored bool, matchedAt int) {
+	pathComponents, isDir, ok := g.splitPath(path)
+	if !ok {
+		return false, -1
+	}
+
+	deciding := g.decidingRule(pathComponents, isDir)
+	if deciding == -1 {
+		return false, -1
+	}
+	_, matchedAt = g.Rules[deciding].matchesPathAt(isDir, pathComponents, &g.options)
+	return !g.Rules[deciding].Negate, matchedAt
+}
+
+// Reports whether a and b give the same result for every path in paths
+// this is only a sample based check, the two can still differ on paths that are not in the sample
+func EquivalentOn(a, b *GitIgnore, paths []string) bool {
+	for _, path := range paths {
+		if a.MatchesPath(path) != b.MatchesPath(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matcher is implemented by types that can decide whether a path is ignored
+type Matcher interface {
+	MatchesPath(path string) bool
+}
+
+// combines an ignore list with an allowlist, see WithAllowlist
+type allowlistMatcher struct {
+	ignore *GitIgnore
+	allow  *GitIgnore
+}
+
+func (m *allowlistMatcher) MatchesPath(path string) bool {
+	return m.ignore.MatchesPath(path) && !m.allow.MatchesPath(path)
+}
+
+// Returns a matcher that ignores the paths matched by g, unless they are matched by allow
+// paths matched by allow are always kept, regardless of the order of the rules, or of their parent directories being ignored
+func (g *GitIgnore) WithAllowlist(allow *GitIgnore) Matcher {
+	return &allowlistMatcher{ignore: g, allow: allow}
+}
+
+// Returns true if any rule that isn't a negation matches the path, negations are not evaluated at all
+// useful for showing which paths are targeted by ignore rules, even if some of them are re-included
+func (g *GitIgnore) MatchesPathIgnoringNegations(path string) bool {
+	pathComponents, isDir, ok := g.splitPath(path)
+	if !ok {
+		return false
+	}
+
+	for i := range g.Rules {
+		if !g.Rules[i].Negate && g.Rules[i].matchesPath(isDir, pathComponents, &g.options) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether any of the rules is a negation (i.e. starts with '!')
+func (g *GitIgnore) HasNegations() bool {
+	for i := range g.Rules {
+		if g.Rules[i].Negate {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true as soon as a rule matches the path, without checking the remaining rules
+// this is only equivalent to MatchesPath if HasNegations() is false,
+// since a later negation could re-include the path
+func (g *GitIgnore) AnyMatch(path string) bool {
+	pathComponents, isDir, ok := g.splitPath(path)
+	if !ok {
+		return false
+	}
+
+	for i := range g.Rules {
+		if g.Rules[i].matchesPath(isDir, pathComponents, &g.options) {
+			return true
+		}
+	}
+	return false
 }
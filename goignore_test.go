@@ -2,6 +2,8 @@ package goignore
 
 import (
 	"fmt"
+	"math/rand"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -360,6 +362,1497 @@ func TestFolders(t *testing.T) {
 	assert.Equal(t, false, ignoreObject.MatchesPath("Fizz/Folder"), "should not match Fizz/Folder")
 }
 
+func TestCaseFolding(t *testing.T) {
+	gitIgnore := []string{"build/output.txt"}
+
+	ignoreObject := CompileIgnoreLinesWithOptions(gitIgnore, Options{})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/output.txt"), "should match build/output.txt")
+	assert.Equal(t, false, ignoreObject.MatchesPath("BUILD/output.txt"), "should not match BUILD/output.txt")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/OUTPUT.txt"), "should not match build/OUTPUT.txt")
+
+	ignoreObject = CompileIgnoreLinesWithOptions(gitIgnore, Options{FoldDirectories: true})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("BUILD/output.txt"), "should match BUILD/output.txt")
+	assert.Equal(t, false, ignoreObject.MatchesPath("Build/Output.txt"), "should not match Build/Output.txt")
+
+	ignoreObject = CompileIgnoreLinesWithOptions(gitIgnore, Options{FoldFiles: true})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/OUTPUT.txt"), "should match build/OUTPUT.txt")
+	assert.Equal(t, false, ignoreObject.MatchesPath("Build/Output.txt"), "should not match Build/Output.txt")
+
+	ignoreObject = CompileIgnoreLinesWithOptions(gitIgnore, Options{FoldDirectories: true, FoldFiles: true})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("Build/Output.TXT"), "should match Build/Output.TXT")
+
+	// a trailing slash makes the last component a directory
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{"[a-c]ache/"}, Options{FoldDirectories: true})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("CACHE/"), "should match CACHE/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("Cache/file"), "should match Cache/file")
+}
+
+func TestDirMatcher(t *testing.T) {
+	gitIgnore := []string{"build/", "*.log", "/docs", "!docs/keep/", "cache/*/", "**/tmp/"}
+	ignoreObject := CompileIgnoreLines(gitIgnore)
+	dirMatcher := ignoreObject.DirMatcher()
+
+	assert.NotNil(t, dirMatcher, "Returned object should not be nil")
+
+	paths := []string{
+		"build", "a/build", "build/x", "x.log", "a/x.log", "docs", "docs/keep", "docs/other",
+		"a/docs", "cache", "cache/a", "cache/a/b", "tmp", "a/b/tmp", "src", "src/main",
+	}
+	for _, path := range paths {
+		assert.Equal(t, ignoreObject.MatchesPath(path+"/"), dirMatcher.MatchesPath(path), "dir matcher should agree on "+path)
+		assert.Equal(t, ignoreObject.MatchesPath(path+"/"), dirMatcher.MatchesPath(path+"/"), "dir matcher should agree on "+path+"/")
+	}
+
+	// the original matcher should be left untouched
+	assert.Equal(t, false, ignoreObject.MatchesPath("build"), "should not match build file")
+	assert.Equal(t, true, dirMatcher.MatchesPath("build"), "should match build directory")
+
+	// the rules keep their directory-only flag
+	assert.Equal(t, ignoreObject.Describe(), dirMatcher.Describe(), "should describe the same rules")
+	assert.Equal(t, ignoreObject.ToGlobs(""), dirMatcher.ToGlobs(""), "should emit the same globs")
+	assert.Equal(t, true, dirMatcher.Rules[0].OnlyDirectory, "build/ should stay directory-only")
+}
+
+func TestRelativeDirOnly(t *testing.T) {
+	gitIgnore := []string{"/build/"}
+	ignoreObject := CompileIgnoreLines(gitIgnore)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/"), "should match build directory")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/x"), "should match contents of build")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build"), "should not match build file")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/build/"), "should not match nested build directory")
+}
+
+func TestEscapedSpace(t *testing.T) {
+	gitIgnore := []string{"my\\ folder/file.txt"}
+	ignoreObject := CompileIgnoreLines(gitIgnore)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("my folder/file.txt"), "should match my folder/file.txt")
+	assert.Equal(t, false, ignoreObject.MatchesPath("myfolder/file.txt"), "should not match myfolder/file.txt")
+	assert.Equal(t, false, ignoreObject.MatchesPath("my\\ folder/file.txt"), "should not match my\\ folder/file.txt")
+}
+
+func TestDescribe(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{".gitignore": "# build output\n/build/\n*.log\n\n!important.log\ndocs/*.html\n\\*literal\n"})
+	filename := filepath.Join(dir, ".gitignore")
+
+	ignoreObject, err := CompileIgnoreFile(filename)
+	assert.Nil(t, err, "should be able to compile the .gitignore file")
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+
+	assert.Equal(t, []RuleInfo{
+		{Pattern: "/build/", Line: 2, Source: filename, DirOnly: true, Anchored: true, Literal: true},
+		{Pattern: "*.log", Line: 3, Source: filename},
+		{Pattern: "!important.log", Line: 5, Source: filename, Negated: true, Literal: true},
+		{Pattern: "docs/*.html", Line: 6, Source: filename, Anchored: true},
+		{Pattern: "\\*literal", Line: 7, Source: filename, Literal: true},
+	}, ignoreObject.Describe())
+}
+
+func TestDescribe_LeadingDoubleStar(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"**/cache", "**/a/b", "./**/foo", "cache"})
+
+	assert.Equal(t, []RuleInfo{
+		{Pattern: "**/cache", Line: 1},
+		{Pattern: "**/a/b", Line: 2},
+		{Pattern: "./**/foo", Line: 3},
+		{Pattern: "cache", Line: 4, Literal: true},
+	}, ignoreObject.Describe(), "a leading ** should not be reported as literal")
+}
+
+func TestRelativeExactPath(t *testing.T) {
+	gitIgnore := []string{"/a/b"}
+	ignoreObject := CompileIgnoreLines(gitIgnore)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b"), "should match a/b")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b/c"), "should match a/b/c")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/bc"), "should not match a/bc")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b/"), "should match a/b/")
+}
+
+func TestAnyMatch(t *testing.T) {
+	gitIgnore := []string{"*.log", "build/", "/docs/*.html", "**/tmp"}
+	ignoreObject := CompileIgnoreLines(gitIgnore)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.HasNegations(), "should not have negations")
+
+	paths := []string{"a.log", "a/b.log", "build", "build/", "build/x", "docs/a.html", "a/docs/a.html", "tmp", "a/tmp/b", "src/main.go"}
+	for _, path := range paths {
+		assert.Equal(t, ignoreObject.MatchesPath(path), ignoreObject.AnyMatch(path), "AnyMatch should agree with MatchesPath on "+path)
+	}
+
+	// with negations the two can disagree
+	ignoreObject = CompileIgnoreLines([]string{"*.log", "!keep.log"})
+	assert.Equal(t, true, ignoreObject.HasNegations(), "should have negations")
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep.log"), "should not match keep.log")
+	assert.Equal(t, true, ignoreObject.AnyMatch("keep.log"), "AnyMatch should stop at *.log")
+}
+
+func TestReplaceLine(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{
+		"*.log",
+		"# comment",
+		"build/",
+		"!keep.log",
+	})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.log"), "should match a.log")
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep.log"), "should not match keep.log")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/x"), "should match build/x")
+
+	// replace a rule in place
+	err := ignoreObject.ReplaceLine(2, "dist/")
+	assert.Nil(t, err, "should replace line 2")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/x"), "should not match build/x")
+	assert.Equal(t, true, ignoreObject.MatchesPath("dist/x"), "should match dist/x")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.log"), "should still match a.log")
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep.log"), "should still not match keep.log")
+
+	// turn the comment into a rule, it must keep its position before the negation
+	err = ignoreObject.ReplaceLine(1, "keep.log")
+	assert.Nil(t, err, "should replace line 1")
+	assert.Equal(t, 4, len(ignoreObject.Rules), "should have 4 rules")
+	assert.Equal(t, "keep.log", ignoreObject.Rules[1].Pattern, "new rule should be the second one")
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep.log"), "negation should still win")
+
+	// turn the negation into a comment, removing the rule
+	err = ignoreObject.ReplaceLine(3, "# !keep.log")
+	assert.Nil(t, err, "should replace line 3")
+	assert.Equal(t, 3, len(ignoreObject.Rules), "should have 3 rules")
+	assert.Equal(t, true, ignoreObject.MatchesPath("keep.log"), "should match keep.log")
+
+	assert.NotNil(t, ignoreObject.ReplaceLine(-1, "foo"), "should reject negative index")
+	assert.NotNil(t, ignoreObject.ReplaceLine(4, "foo"), "should reject index past the last line")
+}
+
+func TestNormalizeUnicode(t *testing.T) {
+	nfc := "caf\u00e9"  // é as a single code point
+	nfd := "cafe\u0301" // e followed by a combining acute accent
+
+	ignoreObject := CompileIgnoreLines([]string{nfc + "/"})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath(nfc+"/menu.txt"), "should match NFC path")
+	assert.Equal(t, false, ignoreObject.MatchesPath(nfd+"/menu.txt"), "should not match NFD path without normalization")
+
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{nfc + "/"}, Options{NormalizeUnicode: true})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath(nfc+"/menu.txt"), "should match NFC path")
+	assert.Equal(t, true, ignoreObject.MatchesPath(nfd+"/menu.txt"), "should match NFD path")
+
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{nfd + "/"}, Options{NormalizeUnicode: true})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath(nfc+"/menu.txt"), "should match NFC path with NFD pattern")
+	assert.Equal(t, true, ignoreObject.MatchesPath(nfd+"/"), "should match NFD directory with NFD pattern")
+}
+
+func TestMatchDetail(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"**/foo"})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+
+	ignored, matchedAt := ignoreObject.MatchDetail("a/b/foo")
+	assert.Equal(t, true, ignored, "should match a/b/foo")
+	assert.Equal(t, 2, matchedAt, "should anchor at the foo component")
+
+	ignored, matchedAt = ignoreObject.MatchDetail("a/foo/b")
+	assert.Equal(t, true, ignored, "should match a/foo/b")
+	assert.Equal(t, 1, matchedAt, "should anchor at the foo component")
+
+	ignored, matchedAt = ignoreObject.MatchDetail("a/b")
+	assert.Equal(t, false, ignored, "should not match a/b")
+	assert.Equal(t, -1, matchedAt, "should not report an anchor")
+
+	ignoreObject = CompileIgnoreLines([]string{"bar", "/x/y", "*.log", "!a/keep.log"})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+
+	ignored, matchedAt = ignoreObject.MatchDetail("a/b/bar/c")
+	assert.Equal(t, true, ignored, "should match a/b/bar/c")
+	assert.Equal(t, 2, matchedAt, "floating rule should anchor at the bar component")
+
+	ignored, matchedAt = ignoreObject.MatchDetail("x/y/z")
+	assert.Equal(t, true, ignored, "should match x/y/z")
+	assert.Equal(t, 0, matchedAt, "relative rule should anchor at the root")
+
+	ignored, matchedAt = ignoreObject.MatchDetail("a/keep.log")
+	assert.Equal(t, false, ignored, "should not match a/keep.log")
+	assert.Equal(t, 0, matchedAt, "negation should be the deciding rule")
+
+	for _, path := range []string{"a/b/bar/c", "x/y/z", "a/keep.log", "b/keep.log", "main.go", "../x"} {
+		ignored, _ = ignoreObject.MatchDetail(path)
+		assert.Equal(t, ignoreObject.MatchesPath(path), ignored, "should agree with MatchesPath on "+path)
+	}
+}
+
+func TestCharacterClassLiteralDash(t *testing.T) {
+	gitIgnore := []string{"[a-]", "x[-b]"}
+	ignoreObject := CompileIgnoreLines(gitIgnore)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a"), "should match a")
+	assert.Equal(t, true, ignoreObject.MatchesPath("-"), "should match -")
+	assert.Equal(t, false, ignoreObject.MatchesPath("b"), "should not match b")
+	assert.Equal(t, true, ignoreObject.MatchesPath("x-"), "should match x-")
+	assert.Equal(t, true, ignoreObject.MatchesPath("xb"), "should match xb")
+	assert.Equal(t, false, ignoreObject.MatchesPath("xa"), "should not match xa")
+}
+
+func TestCompileDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".git/info/exclude": "*.local\nsecret.txt\n",
+		".gitignore":        "build/\n!secret.txt\n",
+	})
+
+	ignoreObject, err := CompileDir(dir)
+	assert.Nil(t, err, "should compile the directory")
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("config.local"), "should match config.local from exclude")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/x"), "should match build/x from .gitignore")
+	assert.Equal(t, false, ignoreObject.MatchesPath("secret.txt"), ".gitignore should take precedence over exclude")
+	assert.Equal(t, filepath.Join(dir, ".git", "info", "exclude"), ignoreObject.Rules[0].Source, "first rule should come from exclude")
+
+	// only a .gitignore, no repository
+	dir = t.TempDir()
+	writeTree(t, dir, map[string]string{".gitignore": "*.log\n"})
+
+	ignoreObject, err = CompileDir(dir)
+	assert.Nil(t, err, "should compile the directory")
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should have 1 rule")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.log"), "should match a.log")
+
+	// only .git/info/exclude
+	dir = t.TempDir()
+	writeTree(t, dir, map[string]string{".git/info/exclude": "*.local\n"})
+
+	ignoreObject, err = CompileDir(dir)
+	assert.Nil(t, err, "should compile the directory")
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should have 1 rule")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.local"), "should match a.local")
+
+	// nothing at all
+	ignoreObject, err = CompileDir(t.TempDir())
+	assert.Nil(t, err, "should compile an empty directory")
+	assert.Equal(t, 0, len(ignoreObject.Rules), "should have no rules")
+}
+
+func TestCompileDir_GitFile(t *testing.T) {
+	// a submodule, .git points to the git directory inside the parent repository
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".git/modules/sub/info/exclude": "*.local\n",
+		"sub/.git":                      "gitdir: ../.git/modules/sub\n",
+		"sub/.gitignore":                "build/\n",
+	})
+
+	ignoreObject, err := CompileDir(filepath.Join(root, "sub"))
+	assert.Nil(t, err, "should compile a submodule")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.local"), "should match a.local from the exclude of the submodule")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/x"), "should match build/x from .gitignore")
+
+	// a worktree, the exclude file is shared with the main repository
+	root = t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main/.git/info/exclude":           "*.tmp\n",
+		"main/.git/worktrees/wt/commondir": "../..\n",
+		"wt/.git":                          "gitdir: " + filepath.Join(root, "main", ".git", "worktrees", "wt") + "\n",
+	})
+
+	ignoreObject, err = CompileDir(filepath.Join(root, "wt"))
+	assert.Nil(t, err, "should compile a worktree")
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should have 1 rule")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.tmp"), "should match a.tmp from the exclude of the main repository")
+
+	// a .git file that is not a gitdir pointer
+	root = t.TempDir()
+	writeTree(t, root, map[string]string{".git": "not a repository\n"})
+	ignoreObject, err = CompileDir(root)
+	assert.Nil(t, err, "should compile a directory with an unrelated .git file")
+	assert.Equal(t, 0, len(ignoreObject.Rules), "should have no rules")
+}
+
+func TestRuleDeeperThanPath(t *testing.T) {
+	gitIgnore := []string{"build/output"}
+	ignoreObject := CompileIgnoreLines(gitIgnore)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build"), "should not match the parent build")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/"), "should not match the parent build/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/output"), "should match build/output")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/output/x"), "should match descendants of build/output")
+	assert.Equal(t, false, ignoreObject.MatchesPath("other/build/output"), "should not match nested build/output")
+}
+
+func TestWithAllowlist(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"build/", "*.log", "!debug.log"})
+	allowObject := CompileIgnoreLines([]string{"important.log", "build/keep/", "*.md"})
+	matcher := ignoreObject.WithAllowlist(allowObject)
+
+	assert.NotNil(t, matcher, "Returned object should not be nil")
+	assert.Equal(t, true, matcher.MatchesPath("a.log"), "should ignore a.log")
+	assert.Equal(t, false, matcher.MatchesPath("debug.log"), "negation should still work")
+	assert.Equal(t, false, matcher.MatchesPath("important.log"), "allowlist should keep important.log")
+	assert.Equal(t, false, matcher.MatchesPath("a/important.log"), "allowlist should keep a/important.log")
+	assert.Equal(t, true, matcher.MatchesPath("build/x"), "should ignore build/x")
+	assert.Equal(t, false, matcher.MatchesPath("build/keep/x"), "allowlist should keep files under build/keep")
+	assert.Equal(t, false, matcher.MatchesPath("build/README.md"), "allowlist should keep files under ignored build")
+	assert.Equal(t, false, matcher.MatchesPath("src/main.go"), "should not ignore src/main.go")
+}
+
+func TestDoubleStarDirOnly(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"a/**/"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b/"), "should match a/b/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b/c/"), "should match a/b/c/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b/c"), "should match files inside a/b/")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/b"), "should not match the file a/b")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/"), "should not match a/ itself")
+	assert.Equal(t, false, ignoreObject.MatchesPath("x/a/b/"), "should not match nested a")
+
+	ignoreObject = CompileIgnoreLines([]string{"**/a/**"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("x/a/y"), "should match x/a/y")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/y"), "should match a/y")
+	assert.Equal(t, true, ignoreObject.MatchesPath("x/y/a/b/c"), "should match x/y/a/b/c")
+	assert.Equal(t, false, ignoreObject.MatchesPath("x/a"), "should not match x/a")
+	assert.Equal(t, false, ignoreObject.MatchesPath("x/y"), "should not match x/y")
+}
+
+func TestWouldChange(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "!keep.log"})
+	paths := []string{"a.log", "keep.log", "build/x", "build/y.txt", "src/main.go"}
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, []string{"build/x", "build/y.txt"}, ignoreObject.WouldChange("build/", paths), "should report the paths under build")
+	assert.Equal(t, []string{"keep.log"}, ignoreObject.WouldChange("keep.log", paths), "should report the re-ignored keep.log")
+	assert.Equal(t, []string{"a.log"}, ignoreObject.WouldChange("!a.log", paths), "should report the re-included a.log")
+	assert.Nil(t, ignoreObject.WouldChange("a.log", paths), "should not report anything for a redundant rule")
+	assert.Nil(t, ignoreObject.WouldChange("# comment", paths), "should not report anything for a comment")
+
+	// the original should be left untouched
+	assert.Equal(t, 2, len(ignoreObject.Rules), "should still have 2 rules")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/x"), "should still not match build/x")
+}
+
+func TestNegationOrder(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"!keep", "*"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath("keep"), "later * should ignore keep")
+	assert.Equal(t, true, ignoreObject.MatchesPath("other"), "should ignore other")
+
+	ignoreObject = CompileIgnoreLines([]string{"*", "!keep"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep"), "later !keep should keep keep")
+	assert.Equal(t, true, ignoreObject.MatchesPath("other"), "should ignore other")
+}
+
+func TestCommentPrefix(t *testing.T) {
+	gitIgnore := []string{
+		"; a comment",
+		";also a comment",
+		"\\;literal.txt",
+		"#not-a-comment",
+		"*.log",
+	}
+	ignoreObject := CompileIgnoreLinesWithOptions(gitIgnore, Options{CommentPrefix: ";"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, 3, len(ignoreObject.Rules), "should skip the ; comments")
+	assert.Equal(t, true, ignoreObject.MatchesPath(";literal.txt"), "should match ;literal.txt")
+	assert.Equal(t, false, ignoreObject.MatchesPath("; a comment"), "should not match the comment")
+	assert.Equal(t, true, ignoreObject.MatchesPath("#not-a-comment"), "# should not start a comment")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.log"), "should match a.log")
+
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{"// comment", "build/"}, Options{CommentPrefix: "//"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should skip the // comment")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/x"), "should match build/x")
+
+	// the default is still #
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{"# comment", ";file"}, Options{})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should skip the # comment")
+	assert.Equal(t, true, ignoreObject.MatchesPath(";file"), "should match ;file")
+}
+
+func TestStarDirOnly(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*/"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, []string{"*"}, ignoreObject.Rules[0].Components, "trailing slash should not add a component")
+	assert.Equal(t, false, ignoreObject.Rules[0].Relative, "*/ should not be anchored")
+	assert.Equal(t, true, ignoreObject.Rules[0].OnlyDirectory, "*/ should only match directories")
+
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo/"), "should match foo/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo/bar"), "should match foo/bar")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/foo/"), "should match a/foo/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/foo"), "should match a/foo, since a is a directory")
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo"), "should not match the file foo")
+}
+
+func TestMatchesPathWith(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "!keep.log"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPathWith("build/x", nil), "should not match build/x without extra patterns")
+	assert.Equal(t, true, ignoreObject.MatchesPathWith("build/x", []string{"build/"}), "extra pattern should exclude build/x")
+	assert.Equal(t, true, ignoreObject.MatchesPathWith("keep.log", []string{"keep.log"}), "extra pattern should override the negation")
+	assert.Equal(t, false, ignoreObject.MatchesPathWith("a.log", []string{"!a.log"}), "extra negation should re-include a.log")
+	assert.Equal(t, true, ignoreObject.MatchesPathWith("a.log", []string{"# comment", "build/"}), "should still match a.log")
+
+	// the matcher itself should not change
+	assert.Equal(t, 2, len(ignoreObject.Rules), "should still have 2 rules")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/x"), "should still not match build/x")
+}
+
+func TestAddPatterns(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "# comment"})
+	ignoreObject.AddPatterns("build/", "", "!a.log")
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, 3, len(ignoreObject.Rules), "should have 3 rules")
+	assert.Equal(t, 3, ignoreObject.Rules[1].Line, "added patterns should continue the line numbers")
+	assert.Equal(t, 5, ignoreObject.Rules[2].Line, "added patterns should continue the line numbers")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/x"), "should match build/x")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a.log"), "should not match a.log")
+	assert.Equal(t, true, ignoreObject.MatchesPath("b.log"), "should match b.log")
+}
+
+func TestNegatedRuleFlags(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"!build/", "!*.log", "!/x/"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, 3, len(ignoreObject.Rules), "should have 3 rules")
+
+	assert.Equal(t, []string{"build"}, ignoreObject.Rules[0].Components, "!build/ should have one component")
+	assert.Equal(t, true, ignoreObject.Rules[0].Negate, "!build/ should be negated")
+	assert.Equal(t, true, ignoreObject.Rules[0].OnlyDirectory, "!build/ should only match directories")
+	assert.Equal(t, false, ignoreObject.Rules[0].Relative, "!build/ should not be anchored")
+
+	assert.Equal(t, []string{"*.log"}, ignoreObject.Rules[1].Components, "!*.log should have one component")
+	assert.Equal(t, true, ignoreObject.Rules[1].Negate, "!*.log should be negated")
+	assert.Equal(t, false, ignoreObject.Rules[1].OnlyDirectory, "!*.log should match files")
+	assert.Equal(t, false, ignoreObject.Rules[1].Relative, "!*.log should not be anchored")
+
+	assert.Equal(t, []string{"x"}, ignoreObject.Rules[2].Components, "!/x/ should have one component")
+	assert.Equal(t, true, ignoreObject.Rules[2].Negate, "!/x/ should be negated")
+	assert.Equal(t, true, ignoreObject.Rules[2].OnlyDirectory, "!/x/ should only match directories")
+	assert.Equal(t, true, ignoreObject.Rules[2].Relative, "!/x/ should be anchored")
+
+	// without a preceding exclusion, the negations are inert
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/"), "should not match build/")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a.log"), "should not match a.log")
+	assert.Equal(t, false, ignoreObject.MatchesPath("x/"), "should not match x/")
+
+	ignoreObject = CompileIgnoreLines([]string{"*", "!build/", "!*.log", "!/x/"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/"), "should re-include build/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build"), "should not re-include the file build")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a.log"), "should re-include a.log")
+	assert.Equal(t, false, ignoreObject.MatchesPath("x/"), "should re-include x/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("x"), "should not re-include the file x")
+	assert.Equal(t, true, ignoreObject.MatchesPath("other"), "should match other")
+}
+
+func TestCompileIgnoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"exclude":    "*.local\n# comment\n*.tmp\n",
+		".gitignore": "build/\n!keep.tmp\n*.log\n",
+	})
+	exclude := filepath.Join(dir, "exclude")
+	gitignore := filepath.Join(dir, ".gitignore")
+
+	ignoreObject, err := CompileIgnoreFiles(exclude, gitignore)
+	assert.Nil(t, err, "should compile both files")
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, 5, len(ignoreObject.Rules), "should have 5 rules")
+	assert.Equal(t, 0, ignoreObject.Rules[1].Layer, "rules of the first file should be in layer 0")
+	assert.Equal(t, 1, ignoreObject.Rules[2].Layer, "rules of the second file should be in layer 1")
+
+	ignored, rule := ignoreObject.MatchesPathHow("a.tmp")
+	assert.Equal(t, true, ignored, "should match a.tmp")
+	assert.Equal(t, exclude, rule.Source, "a.tmp should be ignored by the first file")
+	assert.Equal(t, 3, rule.Line, "a.tmp should be ignored by line 3")
+
+	ignored, rule = ignoreObject.MatchesPathHow("a/b.log")
+	assert.Equal(t, true, ignored, "should match a/b.log")
+	assert.Equal(t, gitignore, rule.Source, "a/b.log should be ignored by the second file")
+	assert.Equal(t, 3, rule.Line, "a/b.log should be ignored by line 3")
+
+	ignored, rule = ignoreObject.MatchesPathHow("keep.tmp")
+	assert.Equal(t, false, ignored, "should not match keep.tmp")
+	assert.Equal(t, "!keep.tmp", rule.Pattern, "keep.tmp should be re-included by the negation")
+	assert.Equal(t, gitignore, rule.Source, "keep.tmp should be re-included by the second file")
+
+	ignored, rule = ignoreObject.MatchesPathHow("src/main.go")
+	assert.Equal(t, false, ignored, "should not match src/main.go")
+	assert.Nil(t, rule, "no rule should match src/main.go")
+
+	_, err = CompileIgnoreFiles(exclude, filepath.Join(dir, "missing"))
+	assert.NotNil(t, err, "should fail for a missing file")
+}
+
+func TestMerge(t *testing.T) {
+	first := CompileIgnoreLines([]string{"*.log"})
+	second := CompileIgnoreLines([]string{"!keep.log", "build/"})
+	ignoreObject := Merge(first, second)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, 3, len(ignoreObject.Rules), "should have 3 rules")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.log"), "should match a.log")
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep.log"), "later negation should win")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/x"), "should match build/x")
+
+	assert.Equal(t, 0, len(Merge().Rules), "merging nothing should give an empty matcher")
+}
+
+// a ruleset in the style of real world .gitignore files, mostly made of "**/" rules
+var realisticRules = []string{
+	"**/node_modules", "**/bower_components", "**/.npm", "**/.eslintcache", "**/.cache", "**/.parcel-cache",
+	"**/.next", "**/.nuxt", "**/dist", "**/.serverless", "**/.fusebox", "**/.dynamodb", "**/.tern-port",
+	"**/.vscode-test", "**/.yarn/cache", "**/.yarn/unplugged", "**/.pnp.*", "**/logs", "**/*.log",
+	"**/npm-debug.log*", "**/yarn-debug.log*", "**/pids", "**/*.pid", "**/*.seed", "**/*.pid.lock",
+	"**/lib-cov", "**/coverage", "**/*.lcov", "**/.nyc_output", "**/.grunt", "**/build/Release",
+	"**/jspm_packages", "**/web_modules", "**/*.tsbuildinfo", "**/.env", "**/.env.test", "**/__pycache__",
+	"**/*.py[cod]", "**/*$py.class", "**/*.so", "**/.Python", "**/develop-eggs", "**/eggs", "**/.eggs",
+	"**/*.egg-info", "**/.installed.cfg", "**/*.egg", "**/.DS_Store", "**/Thumbs.db", "**/*.swp",
+	"**/a/**/b", "**/src/**/generated", "/out", "!**/logs/keep.log", "tmp/", "**/*.tmp", "!important.tmp",
+}
+
+var realisticPaths = []string{
+	"src/index.js", "src/components/App.js", "node_modules/react/index.js", "packages/a/node_modules/b/c.js",
+	"logs/app.log", "logs/keep.log", "a/logs/keep.log", "dist/", "dist/bundle.js", "app/dist/main.css",
+	"lib/__pycache__/mod.cpython-311.pyc", "lib/mod.pyc", "lib/mod.py", ".env", "config/.env.test",
+	"out/x", "src/out/x", "tmp/", "tmp/x", "a/tmp/", "x.tmp", "important.tmp", "a/x/y/b", "a/b",
+	"src/x/generated/file.go", "src/generated", ".yarn/cache/x.zip", "pkg/.yarn/cache/x.zip",
+	"build/Release/app", "build/Debug/app", "README.md", "docs/.DS_Store", "deep/a/b/c/d/e/f/g/h.txt",
+}
+
+// compiles the patterns without collapsing leading "**" components and without the exact fast path
+func compileNaive(patterns []string) *GitIgnore {
+	ignoreObject := CompileIgnoreLines(patterns)
+	for i := range ignoreObject.Rules {
+		rule := &ignoreObject.Rules[i]
+		pattern := strings.TrimPrefix(rule.Pattern, "!")
+		rule.Relative = strings.HasPrefix(pattern, "/")
+		rule.Components = mySplit(strings.TrimPrefix(pattern, "/"), '/')
+		rule.Relative = rule.Relative || len(rule.Components) > 1
+		rule.exact = false
+	}
+	return ignoreObject
+}
+
+func TestDoubleStarCollapse(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"**/foo", "**/**/bar/", "**/a/b", "**", "!/**/c"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, []string{"foo"}, ignoreObject.Rules[0].Components, "leading ** should be dropped")
+	assert.Equal(t, false, ignoreObject.Rules[0].Relative, "**/foo should be floating")
+	assert.Equal(t, true, ignoreObject.Rules[0].exact, "**/foo should use the exact fast path")
+	assert.Equal(t, []string{"bar"}, ignoreObject.Rules[1].Components, "repeated leading ** should be dropped")
+	assert.Equal(t, true, ignoreObject.Rules[1].OnlyDirectory, "**/**/bar/ should only match directories")
+	assert.Equal(t, []string{"a", "b"}, ignoreObject.Rules[2].Components, "leading ** should be dropped")
+	assert.Equal(t, false, ignoreObject.Rules[2].Relative, "**/a/b should be floating")
+	assert.Equal(t, []string{"**"}, ignoreObject.Rules[3].Components, "a lone ** should be kept")
+	assert.Equal(t, []string{"c"}, ignoreObject.Rules[4].Components, "leading ** after / should be dropped")
+	assert.Equal(t, false, ignoreObject.Rules[4].Relative, "/**/c should be floating")
+
+	optimized := CompileIgnoreLines(realisticRules)
+	naive := compileNaive(realisticRules)
+	for _, path := range realisticPaths {
+		assert.Equal(t, naive.MatchesPath(path), optimized.MatchesPath(path), "optimized matcher should agree on "+path)
+	}
+}
+
+func BenchmarkMatchesPath_Realistic(b *testing.B) {
+	ignoreObject := CompileIgnoreLines(realisticRules)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range realisticPaths {
+			ignoreObject.MatchesPath(path)
+		}
+	}
+}
+
+func BenchmarkMatchesPath_RealisticNaive(b *testing.B) {
+	ignoreObject := compileNaive(realisticRules)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range realisticPaths {
+			ignoreObject.MatchesPath(path)
+		}
+	}
+}
+
+func TestMatchesPathIgnoringNegations(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "!keep.log", "build/", "!build/main"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep.log"), "keep.log should be re-included")
+	assert.Equal(t, true, ignoreObject.MatchesPathIgnoringNegations("keep.log"), "keep.log should be targeted by *.log")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/main"), "build/main should be re-included")
+	assert.Equal(t, true, ignoreObject.MatchesPathIgnoringNegations("build/main"), "build/main should be targeted by build/")
+	assert.Equal(t, true, ignoreObject.MatchesPathIgnoringNegations("a.log"), "a.log should be targeted by *.log")
+	assert.Equal(t, false, ignoreObject.MatchesPathIgnoringNegations("src/main.go"), "src/main.go should not be targeted")
+}
+
+func TestCompileIgnoreReader(t *testing.T) {
+	content := "# comment\r\n*.log\n\n!keep.log\nbuild/"
+	ignoreObject, err := CompileIgnoreReader(strings.NewReader(content), Options{})
+
+	assert.Nil(t, err, "should compile the reader")
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, 3, len(ignoreObject.Rules), "should have 3 rules")
+	assert.Equal(t, 4, ignoreObject.Rules[1].Line, "should count blank lines and comments")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.log"), "should match a.log")
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep.log"), "should not match keep.log")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/x"), "should match build/x without a trailing newline")
+}
+
+func TestLimits_MaxLineLength(t *testing.T) {
+	lines := []string{"12345", "123456", "/a/b/"}
+	options := Options{MaxLineLength: 5}
+
+	ignoreObject := CompileIgnoreLinesWithOptions(lines, options)
+	assert.Equal(t, 2, len(ignoreObject.Rules), "should skip the line over the limit")
+	assert.Equal(t, true, ignoreObject.MatchesPath("12345"), "a line at the limit should be kept")
+	assert.Equal(t, false, ignoreObject.MatchesPath("123456"), "a line over the limit should be skipped")
+	assert.Equal(t, 3, ignoreObject.Rules[1].Line, "skipped lines should still be counted")
+
+	ignoreObject, err := CompileIgnoreReader(strings.NewReader(strings.Join(lines, "\n")), options)
+	assert.Nil(t, err, "lenient mode should not fail")
+	assert.Equal(t, 2, len(ignoreObject.Rules), "should skip the line over the limit")
+
+	options.Strict = true
+	_, err = CompileIgnoreReader(strings.NewReader(strings.Join(lines, "\n")), options)
+	assert.ErrorIs(t, err, ErrLimitExceeded, "strict mode should fail")
+	assert.Contains(t, err.Error(), "line 2", "error should contain the line number")
+
+	// lines longer than the buffer of the reader
+	long := strings.Repeat("a", 10000)
+	ignoreObject, err = CompileIgnoreReader(strings.NewReader(long+"\nb\n"), Options{MaxLineLength: 9999})
+	assert.Nil(t, err, "lenient mode should not fail")
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should skip the long line")
+	assert.Equal(t, 2, ignoreObject.Rules[0].Line, "the rule after the long line should be line 2")
+
+	ignoreObject, err = CompileIgnoreReader(strings.NewReader(long+"\nb\n"), Options{MaxLineLength: 10000})
+	assert.Nil(t, err, "should not fail")
+	assert.Equal(t, 2, len(ignoreObject.Rules), "should keep the long line at the limit")
+	assert.Equal(t, true, ignoreObject.MatchesPath(long), "should match the long line")
+
+	// the '\r' of CRLF line endings doesn't count in any of the entry points
+	ignoreObject = CompileIgnoreLinesWithOptions(strings.Split("abcde\r\nabcdef\r\n", "\n"), options)
+	assert.Equal(t, 1, len(ignoreObject.Rules), "CompileIgnoreLines should not count the '\\r'")
+	for _, content := range []string{"abcde\r\nabcdef\r\n", "abcdef\r\nabcde\r"} {
+		ignoreObject, err = CompileIgnoreReader(strings.NewReader(content), Options{MaxLineLength: 5})
+		assert.Nil(t, err, "should not fail")
+		assert.Equal(t, 1, len(ignoreObject.Rules), "CompileIgnoreReader should not count the '\\r'")
+		assert.Equal(t, true, ignoreObject.MatchesPath("abcde"), "should keep the line at the limit")
+	}
+	ignoreObject, err = CompileIgnoreReader(strings.NewReader("abcde\r\n"), Options{MaxLineLength: 5, Strict: true})
+	assert.Nil(t, err, "strict mode should not count the '\\r'")
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should keep the line at the limit")
+}
+
+func TestLimits_MaxRules(t *testing.T) {
+	lines := []string{"a", "# comment", "", "b", "c"}
+	options := Options{MaxRules: 2}
+
+	ignoreObject := CompileIgnoreLinesWithOptions(lines, options)
+	assert.Equal(t, 2, len(ignoreObject.Rules), "should stop at 2 rules")
+	assert.Equal(t, true, ignoreObject.MatchesPath("b"), "the second rule should be kept")
+	assert.Equal(t, false, ignoreObject.MatchesPath("c"), "the third rule should be skipped")
+
+	ignoreObject.AddPatterns("d")
+	assert.Equal(t, 2, len(ignoreObject.Rules), "AddPatterns should respect the limit")
+
+	options.Strict = true
+	_, err := CompileIgnoreReader(strings.NewReader("a\nb\n"), options)
+	assert.Nil(t, err, "should not fail at the limit")
+	_, err = CompileIgnoreReader(strings.NewReader("a\nb\nc\n"), options)
+	assert.ErrorIs(t, err, ErrLimitExceeded, "strict mode should fail over the limit")
+}
+
+func TestLimits_MaxPatternComponents(t *testing.T) {
+	lines := []string{"a/b/c", "w/x/y/z", "**/p/q"}
+	options := Options{MaxPatternComponents: 3}
+
+	ignoreObject := CompileIgnoreLinesWithOptions(lines, options)
+	assert.Equal(t, 2, len(ignoreObject.Rules), "should skip the rule over the limit")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b/c"), "3 components should be kept")
+	assert.Equal(t, false, ignoreObject.MatchesPath("w/x/y/z"), "4 components should be skipped")
+
+	options.Strict = true
+	_, err := CompileIgnoreReader(strings.NewReader("a/b/c\n"), options)
+	assert.Nil(t, err, "should not fail at the limit")
+	_, err = CompileIgnoreReader(strings.NewReader("a/b/c/d\n"), options)
+	assert.ErrorIs(t, err, ErrLimitExceeded, "strict mode should fail over the limit")
+}
+
+func TestStrictValidation(t *testing.T) {
+	_, err := CompileIgnoreReader(strings.NewReader("*.log\n[abc\n"), Options{Strict: true})
+	assert.ErrorIs(t, err, ErrUnclosedBracket, "strict mode should reject invalid patterns")
+
+	ignoreObject, err := CompileIgnoreReader(strings.NewReader("*.log\n[abc\n"), Options{})
+	assert.Nil(t, err, "lenient mode should accept invalid patterns")
+	assert.Equal(t, 2, len(ignoreObject.Rules), "lenient mode should keep invalid patterns")
+}
+
+func TestMatchesEntry(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*", "!foo/"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesEntry("foo", true), "negation should re-include the directory foo")
+	assert.Equal(t, false, ignoreObject.MatchesEntry("foo/", true), "negation should re-include the directory foo/")
+	assert.Equal(t, false, ignoreObject.MatchesEntry("foo/", false), "trailing slash should still mark a directory")
+	assert.Equal(t, true, ignoreObject.MatchesEntry("foo", false), "negation should not re-include the file foo")
+	assert.Equal(t, true, ignoreObject.MatchesEntry("bar", true), "should match the directory bar")
+	assert.Equal(t, ignoreObject.MatchesPath("foo/"), ignoreObject.MatchesEntry("foo", true), "should agree with MatchesPath")
+	assert.Equal(t, ignoreObject.MatchesPath("foo"), ignoreObject.MatchesEntry("foo", false), "should agree with MatchesPath")
+
+	ignoreObject = CompileIgnoreLines([]string{"build/"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesEntry("build", true), "should match the directory build")
+	assert.Equal(t, false, ignoreObject.MatchesEntry("build", false), "should not match the file build")
+	assert.Equal(t, true, ignoreObject.MatchesEntry("a/build", true), "should match the directory a/build")
+}
+
+func TestStringMatch_TrailingPattern(t *testing.T) {
+	assert.Equal(t, false, stringMatch("ab", "abc"), "trailing literal should have to be consumed")
+	assert.Equal(t, false, stringMatch("ab", "abcd"), "trailing literals should have to be consumed")
+	assert.Equal(t, true, stringMatch("ab", "ab*"), "trailing star should match nothing")
+	assert.Equal(t, true, stringMatch("ab", "ab**"), "trailing stars should match nothing")
+	assert.Equal(t, true, stringMatch("ab", "ab"), "should match itself")
+	assert.Equal(t, false, stringMatch("ab", "ab*c"), "literal after a trailing star should have to be consumed")
+	assert.Equal(t, false, stringMatch("ab", "ab[c]"), "trailing class should have to be consumed")
+}
+
+func TestCollapseSlashes(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"a//b", "/c//d//", "x/y"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, []string{"a", "b"}, ignoreObject.Rules[0].Components, "doubled slashes should be collapsed")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b"), "a//b should match a/b")
+	assert.Equal(t, true, ignoreObject.MatchesPath("c/d/"), "/c//d// should match c/d/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("x//y"), "x/y should match x//y")
+	assert.Equal(t, true, ignoreObject.MatchesPath("x///y/z"), "x/y should match x///y/z")
+
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{"a//b", "x/y"}, Options{ExactSlashes: true})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, []string{"a", "", "b"}, ignoreObject.Rules[0].Components, "doubled slashes should be kept")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/b"), "a//b should not match a/b")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a//b"), "a//b should not match a//b")
+	assert.Equal(t, true, ignoreObject.MatchesPath("x/y"), "x/y should match x/y")
+	assert.Equal(t, false, ignoreObject.MatchesPath("x//y"), "x/y should not match x//y")
+}
+
+func TestEquivalentOn(t *testing.T) {
+	paths := []string{"foo", "foo/", "foo/x", "a/foo", "a/b/foo/c", "bar", "a/bar", "foobar", "a/foobar"}
+
+	assert.Equal(t, true, EquivalentOn(CompileIgnoreLines([]string{"**/foo"}), CompileIgnoreLines([]string{"foo"}), paths), "**/foo should be equivalent to foo")
+	assert.Equal(t, true, EquivalentOn(CompileIgnoreLines([]string{"foo/**"}), CompileIgnoreLines([]string{"/foo/**"}), paths), "foo/** should be equivalent to /foo/**")
+	assert.Equal(t, false, EquivalentOn(CompileIgnoreLines([]string{"foo"}), CompileIgnoreLines([]string{"/foo"}), paths), "foo should not be equivalent to /foo")
+	assert.Equal(t, true, EquivalentOn(CompileIgnoreLines([]string{"foo"}), CompileIgnoreLines([]string{"/foo"}), []string{"foo", "bar"}), "a small sample can miss the difference")
+}
+
+func TestDotDotPaths(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*", "!b", "..", "secret"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPath("../x"), "should not match a path leaving the root")
+	assert.Equal(t, false, ignoreObject.MatchesPath("../secret"), "should not match a path leaving the root")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/../b"), "should match a/../b as b")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/../c"), "should match a/../c as c")
+
+	ignored, err := ignoreObject.MatchesPathErr("../x")
+	assert.Equal(t, false, ignored, "should not match a path leaving the root")
+	assert.ErrorIs(t, err, ErrInvalidPath, "should report a path leaving the root")
+	ignored, err = ignoreObject.MatchesPathErr("a/../c")
+	assert.Equal(t, true, ignored, "should match a/../c as c")
+	assert.Nil(t, err, "should accept a path that stays inside the root")
+	_, err = ignoreObject.MatchesPathErr("/etc/passwd")
+	assert.ErrorIs(t, err, ErrInvalidPath, "should report an absolute path")
+
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{"*", "!b"}, Options{RejectDotDot: true})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/../c"), "should reject a/../c")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/.."), "should reject a/..")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/..c"), "should not reject ..c")
+	_, err = ignoreObject.MatchesPathErr("a/../c")
+	assert.ErrorIs(t, err, ErrInvalidPath, "should report a/../c")
+}
+
+func TestTrailingQuestionMark(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"file?"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPath("file"), "? should require a character")
+	assert.Equal(t, true, ignoreObject.MatchesPath("file1"), "should match file1")
+	assert.Equal(t, true, ignoreObject.MatchesPath("fileX"), "should match fileX")
+	assert.Equal(t, false, ignoreObject.MatchesPath("file12"), "? should only match one character")
+
+	assert.Equal(t, false, stringMatch("file", "file?"), "? should require a character")
+	assert.Equal(t, false, stringMatch("file", "file?*"), "? should require a character before a star")
+	assert.Equal(t, true, stringMatch("file12", "file?*"), "star should match the rest")
+}
+
+func TestMergeLayers(t *testing.T) {
+	base := CompileIgnoreLines([]string{"*.log", "*.tmp"})
+	repo := CompileIgnoreLines([]string{"build/"})
+	local := CompileIgnoreLines([]string{"!keep.log", "secret"})
+	ignoreObject := Merge(base, repo, local)
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	layers := make([]int, len(ignoreObject.Rules))
+	for i, rule := range ignoreObject.Rules {
+		layers[i] = rule.Layer
+	}
+	assert.Equal(t, []int{0, 0, 1, 2, 2}, layers, "each rule should record its layer")
+	assert.Equal(t, 2, ignoreObject.Describe()[3].Layer, "Describe should report the layer")
+
+	// layers survive merging again
+	ignoreObject = Merge(Merge(base, repo), CompileIgnoreLines(nil), local)
+	layers = layers[:0]
+	for _, rule := range ignoreObject.Rules {
+		layers = append(layers, rule.Layer)
+	}
+	assert.Equal(t, []int{0, 0, 1, 3, 3}, layers, "nested layers should be kept and following ones shifted")
+
+	// the merged inputs are left untouched
+	assert.Equal(t, 0, local.Rules[0].Layer, "merging should not modify the inputs")
+}
+
+func TestAddPatterns_Merged(t *testing.T) {
+	ignoreObject := Merge(CompileIgnoreLines([]string{"*.log", "*.tmp"}), CompileIgnoreLines([]string{"build/"}))
+	ignoreObject.AddPatterns("# comment", "!keep.log")
+
+	assert.Equal(t, 4, len(ignoreObject.Rules), "should have 4 rules")
+	assert.Equal(t, 2, ignoreObject.Rules[3].Layer, "added patterns should get a layer after the sources")
+	assert.Equal(t, 2, ignoreObject.Rules[3].Line, "added patterns should be numbered from line 1 in their layer")
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep.log"), "the added negation should take precedence")
+
+	dirMatcher := ignoreObject.DirMatcher()
+	dirMatcher.AddPatterns("cache")
+	assert.Equal(t, 2, dirMatcher.Rules[4].Layer, "a DirMatcher should keep adding to the same layer")
+	assert.Equal(t, 3, dirMatcher.Rules[4].Line, "a DirMatcher should keep the line numbers")
+}
+
+func TestTrailingDoubleStar(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"vendor/**"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPath("vendor"), "vendor/** should not match the file vendor")
+	assert.Equal(t, false, ignoreObject.MatchesPath("vendor/"), "vendor/** should not match the directory vendor")
+	assert.Equal(t, true, ignoreObject.MatchesPath("vendor/a"), "vendor/** should match vendor/a")
+	assert.Equal(t, true, ignoreObject.MatchesPath("vendor/a/b"), "vendor/** should match vendor/a/b")
+
+	ignoreObject = CompileIgnoreLines([]string{"vendor/"})
+
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, false, ignoreObject.MatchesPath("vendor"), "vendor/ should not match the file vendor")
+	assert.Equal(t, true, ignoreObject.MatchesPath("vendor/"), "vendor/ should match the directory vendor")
+	assert.Equal(t, true, ignoreObject.MatchesPath("vendor/a"), "vendor/ should match vendor/a")
+	assert.Equal(t, true, ignoreObject.MatchesPath("vendor/a/b"), "vendor/ should match vendor/a/b")
+}
+
+func TestStringMatch_ClassBetweenLiterals(t *testing.T) {
+	assert.Equal(t, true, stringMatch("file0.txt", "file[0-9].txt"), "should match file0.txt")
+	assert.Equal(t, true, stringMatch("file5.txt", "file[0-9].txt"), "should match file5.txt")
+	assert.Equal(t, false, stringMatch("fileA.txt", "file[0-9].txt"), "should not match fileA.txt")
+	assert.Equal(t, false, stringMatch("fileX.txt", "file[0-9].txt"), "should not match fileX.txt")
+	assert.Equal(t, false, stringMatch("file10.txt", "file[0-9].txt"), "class should match a single character")
+	assert.Equal(t, false, stringMatch("file5.txtx", "file[0-9].txt"), "literals after the class should have to be consumed")
+	assert.Equal(t, false, stringMatch("file5", "file[0-9].txt"), "literals after the class should have to match")
+	assert.Equal(t, true, stringMatch("file5.txt", "file[0-9]*.txt"), "star after the class should work")
+	assert.Equal(t, true, stringMatch("file10.txt", "file[0-9][0-9].txt"), "consecutive classes should work")
+
+	ignoreObject := CompileIgnoreLines([]string{"file[0-9].txt"})
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/file0.txt"), "should match a/file0.txt")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/file10.txt"), "should not match a/file10.txt")
+}
+
+func TestEmptyRuleset(t *testing.T) {
+	paths := []string{"a", "a/", "a/b/c.txt", ".git/", "*", "."}
+	for _, ignoreObject := range []*GitIgnore{
+		CompileIgnoreLines(nil),
+		CompileIgnoreLines([]string{"", "# only a comment", "   "}),
+		Merge(),
+	} {
+		assert.Equal(t, 0, len(ignoreObject.Rules), "should have no rules")
+		for _, path := range paths {
+			assert.Equal(t, false, ignoreObject.MatchesPath(path), "MatchesPath should not match "+path)
+			assert.Equal(t, false, ignoreObject.MatchesEntry(path, true), "MatchesEntry should not match "+path)
+			assert.Equal(t, false, ignoreObject.MatchesPathGit(path, true), "MatchesPathGit should not match "+path)
+			assert.Equal(t, false, ignoreObject.AnyMatch(path), "AnyMatch should not match "+path)
+
+			ignored, rule := ignoreObject.MatchesPathHow(path)
+			assert.Equal(t, false, ignored, "MatchesPathHow should not match "+path)
+			assert.Nil(t, rule, "MatchesPathHow should not return a rule for "+path)
+		}
+
+		assert.Equal(t, paths, ignoreObject.FilterPaths(paths), "FilterPaths should keep everything")
+		assert.Equal(t, []string{"a", "b"}, ignoreObject.KeepInDir("x", []string{"a", "b"}, map[string]bool{"a": true}), "KeepInDir should keep everything")
+		assert.Equal(t, []string{"./", "a.log", "build/", "build/keep", "build/out", "main.go", "src/", "src/build", "src/lib/", "src/lib/util.go", "src/lib/x.log"},
+			walkKept(t, ignoreObject, walkTree, "."), "WalkDir should walk everything")
+	}
+
+	ignoreObject, err := CompileIgnoreReader(strings.NewReader(""), Options{Strict: true})
+	assert.Nil(t, err, "should compile an empty reader")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a"), "should not match a")
+}
+
+func TestSlashlessDirs(t *testing.T) {
+	patterns := []string{"foo/", "/build/", "*.log"}
+	ignoreObject := CompileIgnoreLinesWithOptions(patterns, Options{SlashlessDirs: true})
+
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo"), "should match foo without a trailing slash")
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo/"), "should match foo/")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/foo"), "should match a/foo without a trailing slash")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build"), "should match build without a trailing slash")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/build"), "anchored rule should still be anchored")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a.log"), "should still match files")
+	assert.Equal(t, false, ignoreObject.MatchesPath("bar"), "should not match bar")
+
+	ignoreObject = CompileIgnoreLines(patterns)
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo"), "should not match foo by default")
+}
+
+func TestSlashlessDirs_Fold(t *testing.T) {
+	patterns := []string{"*.md", "build/output.txt", "cache/"}
+	ignoreObject := CompileIgnoreLinesWithOptions(patterns, Options{FoldFiles: true, SlashlessDirs: true})
+
+	assert.Equal(t, true, ignoreObject.MatchesPath("README.MD"), "FoldFiles should fold the final component")
+	assert.Equal(t, true, ignoreObject.MatchesEntry("README.MD", false), "FoldFiles should fold a file")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/OUTPUT.txt"), "FoldFiles should fold the final component")
+	assert.Equal(t, false, ignoreObject.MatchesPath("BUILD/output.txt"), "FoldFiles should not fold directories")
+	assert.Equal(t, true, ignoreObject.MatchesPath("cache"), "should still match cache without a trailing slash")
+	assert.Equal(t, false, ignoreObject.MatchesPath("CACHE/"), "FoldFiles should not fold a known directory")
+
+	ignoreObject = CompileIgnoreLinesWithOptions(patterns, Options{FoldDirectories: true, SlashlessDirs: true})
+
+	assert.Equal(t, true, ignoreObject.MatchesPath("CACHE"), "FoldDirectories should fold a final component that could be a directory")
+	assert.Equal(t, true, ignoreObject.MatchesPath("CACHE/"), "FoldDirectories should fold a known directory")
+	assert.Equal(t, true, ignoreObject.MatchesPath("BUILD/output.txt"), "FoldDirectories should fold directories")
+}
+
+func TestLiteralComponentPrefix(t *testing.T) {
+	options := &Options{}
+
+	// stringMatch only compares single components, so a literal prefix is not a match
+	assert.Equal(t, false, stringMatch("abcd", "abc"), "abc should not match abcd")
+	assert.Equal(t, false, stringMatch("abc", "abcd"), "abcd should not match abc")
+	assert.Equal(t, true, stringMatch("abc", "abc"), "abc should match abc")
+
+	match, _, _ := matchComponents([]string{"abcd"}, []string{"abc"}, false, options)
+	assert.Equal(t, false, match, "abc should not match the component abcd")
+	match, _, _ = matchComponents([]string{"abc"}, []string{"abc"}, false, options)
+	assert.Equal(t, true, match, "abc should match the component abc")
+
+	// matching descendants is up to matchComponents, which stops after the components of the rule
+	match, final, _ := matchComponents([]string{"abc", "d"}, []string{"abc"}, false, options)
+	assert.Equal(t, true, match, "abc should match the descendant abc/d")
+	assert.Equal(t, false, final, "abc should not be the final component of abc/d")
+	assert.Equal(t, false, matchComponentsExact([]string{"abc", "d"}, []string{"abc"}, false, options), "git-style matching should not match descendants")
+}
+
+func TestDecidingRule_Reverse(t *testing.T) {
+	// the forward scan decidingRule used to do
+	forward := func(g *GitIgnore, pathComponents []string, isDir bool) int {
+		deciding := -1
+		for i := range g.Rules {
+			if g.Rules[i].matchesPath(isDir, pathComponents, &g.options) {
+				deciding = i
+			}
+		}
+		return deciding
+	}
+
+	rules := append([]string{"*", "!*.go", "src/", "!src/keep/", "*.go", "!main.go"}, realisticRules...)
+	paths := append([]string{"main.go", "a.go", "src/a.go", "src/keep/", "src/keep/main.go", "README"}, realisticPaths...)
+	ignoreObject := CompileIgnoreLines(rules)
+	for _, path := range paths {
+		pathComponents, isDir, ok := ignoreObject.splitPath(path)
+		if !ok {
+			continue
+		}
+		assert.Equal(t, forward(ignoreObject, pathComponents, isDir), ignoreObject.decidingRule(pathComponents, isDir), "should find the same rule for "+path)
+	}
+
+	// main.go is matched by "*", "!*.go", "*.go" and "!main.go"
+	ignored, rule := ignoreObject.MatchesPathHow("main.go")
+	assert.Equal(t, false, ignored, "should not match main.go")
+	assert.Equal(t, "!main.go", rule.Pattern, "the last matching rule should decide main.go")
+	ignored, rule = ignoreObject.MatchesPathHow("a.go")
+	assert.Equal(t, true, ignored, "should match a.go")
+	assert.Equal(t, "*.go", rule.Pattern, "a later rule should override the negation")
+}
+
+func TestDotComponents(t *testing.T) {
+	options := &Options{}
+	assert.Equal(t, createRule("build", options), createRule("./build", options), "./build should be the same as build")
+	assert.Equal(t, createRule("/build", options), createRule("/./build", options), "/./build should be the same as /build")
+	assert.Equal(t, createRule("build/", options), createRule("./build/", options), "./build/ should be the same as build/")
+	assert.Equal(t, createRule("a/b", options), createRule("a/./b", options), "a/./b should be the same as a/b")
+
+	plain := CompileIgnoreLines([]string{"build"})
+	dotted := CompileIgnoreLines([]string{"./build"})
+	for _, path := range []string{"build", "build/x", "a/build/x", "builds/x"} {
+		assert.Equal(t, plain.MatchesPath(path), dotted.MatchesPath(path), "./build and build should agree on "+path)
+	}
+	assert.Equal(t, true, dotted.MatchesPath("a/build/x"), "./build should match a/build/x")
+
+	anchored := CompileIgnoreLines([]string{"/./build"})
+	assert.Equal(t, true, anchored.MatchesPath("build/x"), "/./build should match build/x")
+	assert.Equal(t, false, anchored.MatchesPath("a/build/x"), "/./build should not match a/build/x")
+}
+
+func TestMatchesEntryKind(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"build/", "*.log"})
+
+	assert.Equal(t, true, ignoreObject.MatchesEntryKind("build", Dir), "should match the directory build")
+	assert.Equal(t, false, ignoreObject.MatchesEntryKind("build", File), "should not match the file build")
+	assert.Equal(t, true, ignoreObject.MatchesEntryKind("build/x", File), "should match a file inside build")
+	assert.Equal(t, true, ignoreObject.MatchesEntryKind("a.log", File), "should match the file a.log")
+	assert.Equal(t, true, ignoreObject.MatchesEntryKind("a.log", Dir), "should match the directory a.log")
+
+	// symlinks are matched like files, even if they point to a directory
+	assert.Equal(t, false, ignoreObject.MatchesEntryKind("build", Symlink), "should not match the symlink build")
+	assert.Equal(t, true, ignoreObject.MatchesEntryKind("a.log", Symlink), "should match the symlink a.log")
+	for _, path := range []string{"build", "a/build", "build/x", "a.log", "x/a.log", "main.go"} {
+		assert.Equal(t, ignoreObject.MatchesEntryKind(path, File), ignoreObject.MatchesEntryKind(path, Symlink), "a symlink should match like a file on "+path)
+	}
+}
+
+func TestHash(t *testing.T) {
+	rules := []string{"*.log", "!keep.log", "build/", "/dist"}
+	hash := CompileIgnoreLines(rules).Hash()
+
+	assert.Equal(t, hash, CompileIgnoreLines(rules).Hash(), "equal rulesets should hash equal")
+	assert.Equal(t, hash, CompileIgnoreLines([]string{"# comment", "*.log", "", "!keep.log", "build/", "/dist  "}).Hash(), "comments and whitespace should not change the hash")
+	assert.NotEqual(t, hash, CompileIgnoreLines([]string{"!keep.log", "*.log", "build/", "/dist"}).Hash(), "reordered rules should hash differently")
+	assert.NotEqual(t, hash, CompileIgnoreLines([]string{"*.log", "keep.log", "build/", "/dist"}).Hash(), "a changed negation should hash differently")
+	assert.NotEqual(t, hash, CompileIgnoreLines([]string{"*.log", "!keep.log", "build", "/dist"}).Hash(), "a changed directory-only flag should hash differently")
+	assert.NotEqual(t, hash, CompileIgnoreLines([]string{"*.log", "!keep.log", "build/", "dist"}).Hash(), "a changed anchor should hash differently")
+	assert.NotEqual(t, CompileIgnoreLines([]string{"ab", "c"}).Hash(), CompileIgnoreLines([]string{"a", "bc"}).Hash(), "rule boundaries should change the hash")
+	assert.NotEqual(t, hash, CompileIgnoreLines(rules).DirMatcher().Hash(), "a DirMatcher should hash differently")
+	assert.Equal(t, uint64(0xcbf29ce484222325), CompileIgnoreLines(nil).Hash(), "an empty ruleset should hash to the FNV offset basis")
+}
+
+func TestHash_Options(t *testing.T) {
+	rules := []string{"*.log", "!keep.log", "build/", "/dist"}
+	hash := CompileIgnoreLines(rules).Hash()
+
+	assert.Equal(t, hash, CompileIgnoreLinesWithOptions(rules, Options{MaxRules: 10, Strict: true}).Hash(), "options that don't change the results should not change the hash")
+	hashes := map[uint64]string{hash: "default"}
+	for name, options := range map[string]Options{
+		"FoldFiles":            {FoldFiles: true},
+		"FoldDirectories":      {FoldDirectories: true},
+		"NormalizeUnicode":     {NormalizeUnicode: true},
+		"WindowsPaths":         {WindowsPaths: true},
+		"ExactSlashes":         {ExactSlashes: true},
+		"SlashlessDirs":        {SlashlessDirs: true},
+		"RejectDotDot":         {RejectDotDot: true},
+		"MaxWildcardBacktrack": {MaxWildcardBacktrack: 100},
+		"FoldFiles+FoldDirs":   {FoldFiles: true, FoldDirectories: true},
+	} {
+		optionsHash := CompileIgnoreLinesWithOptions(rules, options).Hash()
+		previous, found := hashes[optionsHash]
+		assert.Equal(t, false, found, name+" should not hash like "+previous)
+		hashes[optionsHash] = name
+	}
+	assert.NotEqual(t, CompileIgnoreLines([]string{"^a$"}).Hash(), CompileIgnoreLinesWithOptions([]string{"^a$"}, Options{RegexMode: true}).Hash(), "RegexMode should change the hash")
+}
+
+func TestSingleComponentPath(t *testing.T) {
+	assert.Equal(t, true, CompileIgnoreLines([]string{"README"}).MatchesPath("README"), "README should match README")
+	assert.Equal(t, false, CompileIgnoreLines([]string{"docs/README"}).MatchesPath("README"), "docs/README should not match README")
+	assert.Equal(t, true, CompileIgnoreLines([]string{"*"}).MatchesPath("README"), "* should match README")
+	assert.Equal(t, true, CompileIgnoreLines([]string{"R*"}).MatchesPath("README"), "R* should match README")
+	assert.Equal(t, false, CompileIgnoreLines([]string{"D*"}).MatchesPath("README"), "D* should not match README")
+	assert.Equal(t, true, CompileIgnoreLines([]string{"/README"}).MatchesPath("README"), "/README should match README")
+	assert.Equal(t, false, CompileIgnoreLines([]string{"**/docs/README"}).MatchesPath("README"), "**/docs/README should not match README")
+	assert.Equal(t, false, CompileIgnoreLines([]string{"README/**"}).MatchesPath("README"), "README/** should not match README itself")
+
+	// the root has no components, so nothing matches it
+	for _, pattern := range []string{"*", "**", "/*", "README"} {
+		assert.Equal(t, false, CompileIgnoreLines([]string{pattern}).MatchesPath("."), pattern+" should not match the root")
+		assert.Equal(t, false, CompileIgnoreLines([]string{pattern}).MatchesPath(""), pattern+" should not match an empty path")
+	}
+}
+
+func TestTrailingBackslash(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"foo\\"})
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should compile foo\\")
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo\\"), "should match a literal backslash")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/foo\\"), "should match a/foo\\")
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo"), "should not match foo")
+	assert.Equal(t, false, ignoreObject.MatchesPath("foox"), "should not match foox")
+
+	_, err := CompileIgnoreReader(strings.NewReader("*.log\nfoo\\\n"), Options{Strict: true})
+	assert.ErrorIs(t, err, ErrTrailingBackslash, "strict mode should reject a trailing backslash")
+
+	ignoreObject, err = CompileIgnoreReader(strings.NewReader("foo\\ \n"), Options{Strict: true})
+	assert.Nil(t, err, "strict mode should accept an escaped trailing space")
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo "), "should match foo with a trailing space")
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo"), "should not match foo")
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo\\"), "should not match foo\\")
+}
+
+func TestMatchSegmentVsStdlib(t *testing.T) {
+	// the syntax both agree on, '!', ':' and multi-byte characters are left out on purpose
+	const alphabet = "ab.-*?[]^\\"
+	random := rand.New(rand.NewSource(1))
+	randomString := func(alphabet string, n int) string {
+		b := make([]byte, random.Intn(n+1))
+		for i := range b {
+			b[i] = alphabet[random.Intn(len(alphabet))]
+		}
+		return string(b)
+	}
+
+	checked := 0
+	for i := 0; i < 20000; i++ {
+		pattern := randomString(alphabet, 8)
+		name := randomString("ab.-[]^\\", 6)
+		expected, err := filepath.Match(pattern, name)
+		if err != nil {
+			continue
+		}
+		checked++
+		if !assert.Equal(t, expected, MatchSegment(pattern, name), fmt.Sprintf("should agree with filepath.Match on %q %q", pattern, name)) {
+			break
+		}
+	}
+	assert.Greater(t, checked, 1000, "should check enough valid patterns")
+
+	// where the package intentionally differs
+	assert.Equal(t, true, MatchSegment("[!a]", "b"), "'!' should negate a class")
+	assert.Equal(t, true, MatchSegment("[[:digit:]]", "5"), "should support POSIX classes")
+	assert.Equal(t, true, MatchSegment("[]]", "]"), "a leading ']' should be a literal")
+	assert.Equal(t, false, MatchSegment("[a", "a"), "an invalid pattern should not match")
+}
+
+func BenchmarkMatchSegment(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		MatchSegment("*.[ch]", "matcher.c")
+		MatchSegment("file-?-*.txt", "file-a-output.txt")
+	}
+}
+
+func BenchmarkFilepathMatch(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		filepath.Match("*.[ch]", "matcher.c")
+		filepath.Match("file-?-*.txt", "file-a-output.txt")
+	}
+}
+
+func TestMaxWildcardBacktrack(t *testing.T) {
+	pattern := "*a*a*a*a*a*b"
+	matching := strings.Repeat("a", 10000) + "b"
+	nonMatching := strings.Repeat("a", 10000) + "c"
+
+	ignoreObject := CompileIgnoreLines([]string{pattern})
+	assert.Equal(t, true, ignoreObject.MatchesPath(matching), "should match without a limit")
+	assert.Equal(t, false, ignoreObject.MatchesPath(nonMatching), "should not match without a limit")
+
+	// the limit is hit before the 'b' at the end is reached, so the component doesn't match
+	limited := CompileIgnoreLinesWithOptions([]string{pattern}, Options{MaxWildcardBacktrack: 100})
+	assert.Equal(t, false, limited.MatchesPath(matching), "should not match after exceeding the limit")
+	assert.Equal(t, false, limited.MatchesPath(nonMatching), "should not match after exceeding the limit")
+	assert.Equal(t, true, limited.MatchesPath("aaaaab"), "should still match when the limit is not exceeded")
+	assert.Equal(t, false, stringMatchFold(matching, pattern, false, 100), "should give up after 100 backtracks")
+	assert.Equal(t, true, stringMatchFold(matching, pattern, false, 0), "zero should mean no limit")
+}
+
+func TestLoneStar(t *testing.T) {
+	rule := createRule("*", &Options{})
+	assert.Equal(t, []string{"*"}, rule.Components, "should have a single * component")
+	assert.Equal(t, false, rule.Relative, "should not be relative")
+
+	ignoreObject := CompileIgnoreLines([]string{"*"})
+	for _, path := range []string{"a", "a/", "a/b", "a/b/c", ".hidden", "a/b/c/"} {
+		assert.Equal(t, true, ignoreObject.MatchesPath(path), "* should match "+path)
+	}
+
+	ignoreObject = CompileIgnoreLines([]string{"*", "!keep"})
+	assert.Equal(t, false, ignoreObject.MatchesPath("keep"), "should keep keep")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/keep"), "should keep a/keep")
+	assert.Equal(t, true, ignoreObject.MatchesPath("other"), "should ignore other")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/other"), "should ignore a/other")
+	// the parent directory of keep is ignored, so git can't re-include it
+	assert.Equal(t, true, ignoreObject.MatchesPathGit("a/keep", false), "MatchesPathGit should not re-include a/keep")
+}
+
+func TestEmbeddedSlashAnchoring(t *testing.T) {
+	rule := createRule("foo/bar", &Options{})
+	assert.Equal(t, true, rule.Relative, "foo/bar should be anchored")
+
+	ignoreObject := CompileIgnoreLines([]string{"foo/bar"})
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo/bar"), "should match foo/bar")
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo/bar/"), "should match the directory foo/bar")
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo/bar/baz"), "should match descendants of foo/bar")
+	assert.Equal(t, true, ignoreObject.MatchesPath("foo/bar/baz/qux"), "should match deep descendants of foo/bar")
+	assert.Equal(t, false, ignoreObject.MatchesPath("x/foo/bar"), "should not match below the root")
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo/barbaz"), "should not match foo/barbaz")
+	assert.Equal(t, false, ignoreObject.MatchesPath("foo"), "should not match foo")
+
+	// git agrees for the entries themselves, descendants are covered by the parent exclusion
+	assert.Equal(t, true, ignoreObject.MatchesPathGit("foo/bar/baz", false), "MatchesPathGit should match descendants of foo/bar")
+	assert.Equal(t, false, ignoreObject.MatchesPathGit("x/foo/bar", false), "MatchesPathGit should not match below the root")
+}
+
+func TestMatchesRelativeTo(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "/build/", "docs/*.tmp"})
+
+	ignored, inScope := ignoreObject.MatchesRelativeTo("/repo/sub", "/repo/sub/a.log")
+	assert.Equal(t, true, ignored, "should match a.log")
+	assert.Equal(t, true, inScope, "a.log should be in scope")
+
+	ignored, inScope = ignoreObject.MatchesRelativeTo("/repo/sub/", "/repo/sub/build/")
+	assert.Equal(t, true, ignored, "should match the directory build")
+	assert.Equal(t, true, inScope, "build should be in scope")
+
+	ignored, inScope = ignoreObject.MatchesRelativeTo("/repo", "/repo/sub/build/")
+	assert.Equal(t, false, ignored, "/build/ should be anchored to the base")
+	assert.Equal(t, true, inScope, "sub/build should be in scope")
+
+	ignored, inScope = ignoreObject.MatchesRelativeTo("repo", "repo/docs/a.tmp")
+	assert.Equal(t, true, ignored, "should match relative paths")
+	assert.Equal(t, true, inScope, "docs/a.tmp should be in scope")
+
+	ignored, inScope = ignoreObject.MatchesRelativeTo("/repo/sub", "/repo/other/a.log")
+	assert.Equal(t, false, ignored, "should not match outside of the base")
+	assert.Equal(t, false, inScope, "/repo/other/a.log should not be in scope")
+
+	ignored, inScope = ignoreObject.MatchesRelativeTo("/repo/sub", "/repo/subdir/a.log")
+	assert.Equal(t, false, ignored, "should not match a sibling with the same prefix")
+	assert.Equal(t, false, inScope, "/repo/subdir/a.log should not be in scope")
+
+	ignored, inScope = ignoreObject.MatchesRelativeTo("/repo/sub", "/repo/sub")
+	assert.Equal(t, false, ignored, "should not match the base itself")
+	assert.Equal(t, true, inScope, "the base should be in scope")
+
+	ignored, inScope = ignoreObject.MatchesRelativeTo("/", "/x.log")
+	assert.Equal(t, true, ignored, "should match below the filesystem root")
+	assert.Equal(t, true, inScope, "/x.log should be in scope of /")
+
+	ignored, inScope = ignoreObject.MatchesRelativeTo(".", "../x.log")
+	assert.Equal(t, false, ignored, "should not match outside of the base")
+	assert.Equal(t, false, inScope, "../x.log should not be in scope")
+}
+
+func TestUnicodeCaseFolding(t *testing.T) {
+	assert.Equal(t, true, stringMatchFold("é.txt", "É.txt", true, 0), "É should fold to é")
+	assert.Equal(t, true, stringMatchFold("straße", "STRAẞE", true, 0), "ẞ should fold to ß")
+	assert.Equal(t, true, stringMatchFold("k", "\u212a", true, 0), "the Kelvin sign should fold to k")
+	assert.Equal(t, true, stringMatchFold("Σίσυφος", "*ΊΣΥΦΟΣ", true, 0), "should fold after a star")
+	assert.Equal(t, false, stringMatchFold("é.txt", "É.txt", false, 0), "should not fold without fold")
+
+	// simple case folding is not locale dependent, so the Turkish dotted and dotless i only match themselves
+	assert.Equal(t, true, stringMatchFold("i", "I", true, 0), "I should fold to i")
+	assert.Equal(t, false, stringMatchFold("i", "İ", true, 0), "İ should not fold to i")
+	assert.Equal(t, false, stringMatchFold("I", "ı", true, 0), "ı should not fold to I")
+	assert.Equal(t, true, stringMatchFold("İ", "İ", true, 0), "İ should match itself")
+
+	ignoreObject := CompileIgnoreLinesWithOptions([]string{"Résumé/", "ÖL.txt"}, Options{FoldDirectories: true, FoldFiles: true})
+	assert.Equal(t, true, ignoreObject.MatchesPath("résumé/cv.pdf"), "should fold non-ASCII directories")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/öl.TXT"), "should fold non-ASCII files")
+	assert.Equal(t, false, ignoreObject.MatchesPath("a/ol.txt"), "should not strip accents")
+}
+
+func TestMatchedByWildcard(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "debug.log", "secret.txt", "build/", "**/cache", "file?.txt", "[ab].md", "\\*literal", "!keep.log"})
+
+	assert.Equal(t, false, ignoreObject.MatchedByWildcard("debug.log"), "debug.log is decided by a literal rule")
+	assert.Equal(t, true, ignoreObject.MatchedByWildcard("other.log"), "other.log is decided by *.log")
+	assert.Equal(t, false, ignoreObject.MatchedByWildcard("a/secret.txt"), "secret.txt is a literal rule")
+	assert.Equal(t, false, ignoreObject.MatchedByWildcard("build/x"), "build/ is a literal rule")
+	assert.Equal(t, true, ignoreObject.MatchedByWildcard("a/cache"), "**/cache is a wildcard rule")
+	assert.Equal(t, true, ignoreObject.MatchedByWildcard("file1.txt"), "file?.txt is a wildcard rule")
+	assert.Equal(t, true, ignoreObject.MatchedByWildcard("a.md"), "[ab].md is a wildcard rule")
+	assert.Equal(t, false, ignoreObject.MatchedByWildcard("*literal"), "an escaped '*' is a literal")
+	assert.Equal(t, false, ignoreObject.MatchedByWildcard("keep.log"), "keep.log is not ignored")
+	assert.Equal(t, false, ignoreObject.MatchedByWildcard("main.go"), "main.go is not ignored")
+
+	ignoreObject = CompileIgnoreLines([]string{"./**/foo", "/**/bar"})
+
+	assert.Equal(t, true, ignoreObject.MatchedByWildcard("a/foo"), "./**/foo is a wildcard rule")
+	assert.Equal(t, true, ignoreObject.MatchedByWildcard("a/bar"), "/**/bar is a wildcard rule")
+}
+
+func TestRegexMode(t *testing.T) {
+	ignoreObject := CompileIgnoreLinesWithOptions([]string{
+		"# object files in build",
+		`^build/.*\.o$`,
+		`!^build/keep\.o$`,
+		`\.log$`,
+		`!^important\.log$`,
+		`^important\.log$`,
+		`^tmp/$`,
+		`(unclosed`,
+	}, Options{RegexMode: true})
+
+	assert.Equal(t, 6, len(ignoreObject.Rules), "should skip comments and invalid regexps")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/a.o"), "should match build/a.o")
+	assert.Equal(t, true, ignoreObject.MatchesPath("build/x/b.o"), "should match build/x/b.o")
+	assert.Equal(t, false, ignoreObject.MatchesPath("src/build/a.o"), "^ should anchor to the start of the path")
+	assert.Equal(t, false, ignoreObject.MatchesPath("build/keep.o"), "negation should re-include build/keep.o")
+	assert.Equal(t, true, ignoreObject.MatchesPath("a/b.log"), "should match a/b.log")
+	assert.Equal(t, true, ignoreObject.MatchesPath("important.log"), "the last matching rule should decide")
+	assert.Equal(t, true, ignoreObject.MatchesPath("tmp/"), "should match the directory tmp")
+	assert.Equal(t, false, ignoreObject.MatchesPath("tmp"), "should not match the file tmp")
+	assert.Equal(t, false, ignoreObject.MatchesPath("tmp/x"), "the contents of a directory are not matched implicitly")
+	assert.Equal(t, true, ignoreObject.MatchesPathGit("tmp/x", false), "MatchesPathGit should still exclude the contents of ignored directories")
+	assert.Equal(t, false, ignoreObject.CanSkipDir("tmp"), "should never skip directories")
+
+	walkMatcher := NewWalkMatcher(ignoreObject)
+	assert.Equal(t, true, walkMatcher.MatchesPath("tmp/"), "WalkMatcher should match the directory tmp")
+	assert.Equal(t, false, walkMatcher.MatchesPath("tmp/x"), "WalkMatcher should not cache regex matches")
+
+	_, err := CompileIgnoreReader(strings.NewReader("^ok$\n(unclosed\n"), Options{RegexMode: true, Strict: true})
+	assert.NotNil(t, err, "strict mode should reject invalid regexps")
+	_, err = CompileIgnoreReader(strings.NewReader("/\n# comment\n"), Options{RegexMode: true, Strict: true})
+	assert.Nil(t, err, "strict mode should not validate regexps as patterns")
+	_, err = CompileIgnoreReader(strings.NewReader("; (unclosed\n^ok$\n"), Options{RegexMode: true, Strict: true, CommentPrefix: ";"})
+	assert.Nil(t, err, "strict mode should respect CommentPrefix")
+}
+
+func TestExactRuleMatrix(t *testing.T) {
+	cases := []struct {
+		pattern  string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"config", "config", false, true},
+		{"config", "config", true, true},
+		{"config", "config/", false, true},
+		{"config", "config/x", false, true},
+		{"config", "a/config/x", false, true},
+		{"config/", "config", false, false},
+		{"config/", "config", true, true},
+		{"config/", "config/", false, true},
+		{"config/", "config/x", false, true},
+		{"config/", "a/config", false, false},
+		{"config/", "a/config/x", false, true},
+	}
+	for _, c := range cases {
+		ignoreObject := CompileIgnoreLines([]string{c.pattern})
+		assert.Equal(t, true, ignoreObject.Rules[0].exact, c.pattern+" should be an exact rule")
+		assert.Equal(t, c.expected, ignoreObject.MatchesEntry(c.path, c.isDir), fmt.Sprintf("%s against %s (isDir: %v)", c.pattern, c.path, c.isDir))
+		assert.Equal(t, c.expected, ignoreObject.MatchesPathGit(c.path, c.isDir), fmt.Sprintf("MatchesPathGit: %s against %s (isDir: %v)", c.pattern, c.path, c.isDir))
+	}
+}
+
+func TestCompileIgnoreLinesChecked(t *testing.T) {
+	_, err := CompileIgnoreLinesChecked([]string{"[abc", "*.log"}, Options{Strict: true})
+	assert.ErrorIs(t, err, ErrUnclosedBracket, "strict mode should reject invalid patterns")
+
+	_, err = CompileIgnoreLinesChecked([]string{"*.log", "toolong"}, Options{Strict: true, MaxLineLength: 5})
+	assert.ErrorIs(t, err, ErrLimitExceeded, "strict mode should reject lines over a limit")
+
+	ignoreObject, err := CompileIgnoreLinesChecked([]string{"[abc", "*.log"}, Options{})
+	assert.Nil(t, err, "lenient mode should accept invalid patterns")
+	assert.Equal(t, 2, len(ignoreObject.Rules), "lenient mode should keep invalid patterns")
+
+	ignoreObject, err = CompileIgnoreLinesChecked([]string{"# comment", "*.log", "!keep.log"}, Options{Strict: true})
+	assert.Nil(t, err, "strict mode should accept valid patterns")
+	assert.Equal(t, 2, len(ignoreObject.Rules), "should have 2 rules")
+	assert.Equal(t, 3, ignoreObject.Rules[1].Line, "should number the lines like CompileIgnoreLines")
+}
+
+func TestStrictCommentPrefix(t *testing.T) {
+	ignoreObject, err := CompileIgnoreReader(strings.NewReader("; see [docs\n*.log\n"), Options{CommentPrefix: ";", Strict: true})
+	assert.Nil(t, err, "should not validate comments with a custom prefix")
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should have 1 rule")
+
+	_, err = CompileIgnoreReader(strings.NewReader("#[x\n"), Options{CommentPrefix: ";", Strict: true})
+	assert.ErrorIs(t, err, ErrUnclosedBracket, "# is not a comment with a custom prefix")
+}
+
+func TestReplaceLine_Limits(t *testing.T) {
+	ignoreObject := CompileIgnoreLinesWithOptions([]string{"*.log", "# comment"}, Options{MaxRules: 1})
+	assert.ErrorIs(t, ignoreObject.ReplaceLine(1, "build/"), ErrLimitExceeded, "should not go over MaxRules")
+	assert.Equal(t, 1, len(ignoreObject.Rules), "should still have 1 rule")
+	assert.Nil(t, ignoreObject.ReplaceLine(0, "*.tmp"), "replacing a rule should not count as a new one")
+	assert.Equal(t, "*.tmp", ignoreObject.Rules[0].Pattern, "should replace the rule")
+
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{"*.log"}, Options{MaxLineLength: 5})
+	assert.ErrorIs(t, ignoreObject.ReplaceLine(0, "toolong"), ErrLimitExceeded, "should not go over MaxLineLength")
+	assert.Equal(t, "*.log", ignoreObject.Rules[0].Pattern, "should keep the old rule")
+
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{"*.log"}, Options{Strict: true})
+	assert.ErrorIs(t, ignoreObject.ReplaceLine(0, "[abc"), ErrUnclosedBracket, "should validate in strict mode")
+	assert.Equal(t, "*.log", ignoreObject.Rules[0].Pattern, "should keep the old rule")
+
+	ignoreObject = CompileIgnoreLinesWithOptions([]string{"*.log"}, Options{MaxRules: 1})
+	assert.Equal(t, 0, len(ignoreObject.WouldChange("a.txt", []string{"a.txt"})), "WouldChange should not go over MaxRules")
+	assert.Equal(t, false, ignoreObject.MatchesPathWith("a.txt", []string{"a.txt"}), "MatchesPathWith should not go over MaxRules")
+}
+
+func TestReplaceLine_Merged(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{".gitignore": "build/\n"})
+	repo, err := CompileIgnoreFile(filepath.Join(dir, ".gitignore"))
+	assert.Nil(t, err, "should compile the .gitignore")
+
+	ignoreObject := Merge(CompileIgnoreLines([]string{"*.log", "*.tmp"}), repo)
+	assert.NotNil(t, ignoreObject.ReplaceLine(0, "*.txt"), "the lines of the sources should not be replaceable")
+	assert.Equal(t, "*.log", ignoreObject.Rules[0].Pattern, "should keep the rules of the sources")
+
+	ignoreObject.AddPatterns("# comment", "!keep.log")
+	assert.Nil(t, ignoreObject.ReplaceLine(0, "cache/"), "added lines should be replaceable")
+	assert.Nil(t, ignoreObject.ReplaceLine(1, "!keep.tmp"), "added lines should be replaceable")
+
+	patterns := make([]string, len(ignoreObject.Rules))
+	for i, rule := range ignoreObject.Rules {
+		patterns[i] = rule.Pattern
+	}
+	assert.Equal(t, []string{"*.log", "*.tmp", "build/", "cache/", "!keep.tmp"}, patterns, "should only replace the added lines")
+	assert.Equal(t, 2, ignoreObject.Rules[3].Layer, "the new rule should be in the layer of the added lines")
+	assert.Equal(t, 1, ignoreObject.Rules[3].Line, "the new rule should have the line it replaced")
+	assert.Equal(t, "", ignoreObject.Rules[3].Source, "the new rule should not take the source of a merged rule")
+}
+
+func TestCompileIgnoreFile_LineCount(t *testing.T) {
+	for _, content := range []string{"a\nb\n", "a\nb", "a\n\n", "", "a\r\nb\r\n"} {
+		dir := t.TempDir()
+		writeTree(t, dir, map[string]string{".gitignore": content})
+		fromFile, err := CompileIgnoreFile(filepath.Join(dir, ".gitignore"))
+		assert.Nil(t, err, "should compile the file")
+		fromReader, err := CompileIgnoreReader(strings.NewReader(content), Options{})
+		assert.Nil(t, err, "should compile the reader")
+
+		assert.Equal(t, fromReader.lineCount, fromFile.lineCount, fmt.Sprintf("should count the lines of %q the same way", content))
+		assert.Equal(t, fromReader.ReplaceLine(2, "c") == nil, fromFile.ReplaceLine(2, "c") == nil, fmt.Sprintf("should agree on the ReplaceLine bounds of %q", content))
+		fromFile.AddPatterns("added")
+		fromReader.AddPatterns("added")
+		assert.Equal(t, fromReader.Rules[len(fromReader.Rules)-1].Line, fromFile.Rules[len(fromFile.Rules)-1].Line, fmt.Sprintf("should number added lines of %q the same way", content))
+	}
+}
+
 func FuzzStringMatch(f *testing.F) {
 	f.Add("hello, world!", "hell*[oasd], [[:alpha:]]orld!")
 	f.Add("hello, world!", "hell*[!asd], [![:digit:]]orld!")
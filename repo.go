@@ -0,0 +1,266 @@
+package goignore
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RepoMatcher matches paths of a repository the way `git check-ignore` does
+// it combines the global excludes, .git/info/exclude and the .gitignore of every directory,
+// the .gitignore files are read lazily when a path inside their directory is first matched, and then cached
+// a RepoMatcher is not safe for concurrent use
+type RepoMatcher struct {
+	root     string
+	excludes *GitIgnore
+	ignores  map[string]*GitIgnore
+}
+
+// Returns the path of the global excludes file
+// this is core.excludesFile from the global git config, or if that is not set,
+// $XDG_CONFIG_HOME/git/ignore, or ~/.config/git/ignore if XDG_CONFIG_HOME is not set
+// the global config is $GIT_CONFIG_GLOBAL if it is set, otherwise ~/.config/git/config and ~/.gitconfig, the latter taking precedence
+func GlobalExcludesFile() string {
+	home, homeErr := os.UserHomeDir()
+	config := os.Getenv("XDG_CONFIG_HOME")
+	if config == "" && homeErr == nil {
+		config = filepath.Join(home, ".config")
+	}
+
+	var configFiles []string
+	if global := os.Getenv("GIT_CONFIG_GLOBAL"); global != "" {
+		configFiles = []string{global}
+	} else {
+		if config != "" {
+			configFiles = append(configFiles, filepath.Join(config, "git", "config"))
+		}
+		if homeErr == nil {
+			configFiles = append(configFiles, filepath.Join(home, ".gitconfig"))
+		}
+	}
+	if excludes := configExcludesFile(configFiles...); excludes != "" {
+		return excludes
+	}
+
+	if config == "" {
+		return ""
+	}
+	return filepath.Join(config, "git", "ignore")
+}
+
+// Returns the last core.excludesFile set in the git config files, with a leading "~/" expanded, or "" if none sets it
+// missing files are skipped, only the core section is read, and include directives are not followed
+func configExcludesFile(filenames ...string) string {
+	excludes := ""
+	for _, filename := range filenames {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		inCore := false
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "[") {
+				end := strings.IndexByte(line, ']')
+				if end == -1 {
+					inCore = false
+					continue
+				}
+				inCore = strings.EqualFold(strings.TrimSpace(line[1:end]), "core")
+				// a variable can follow the section header on the same line
+				line = strings.TrimSpace(line[end+1:])
+			}
+			key, value, found := strings.Cut(line, "=")
+			if inCore && found && strings.EqualFold(strings.TrimSpace(key), "excludesFile") {
+				excludes = parseConfigValue(value)
+			}
+		}
+	}
+
+	if rest, found := strings.CutPrefix(excludes, "~/"); found {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, filepath.FromSlash(rest))
+		}
+	}
+	return excludes
+}
+
+// parses the value of a git config variable, removing the quotes, escapes, surrounding whitespace and comment
+func parseConfigValue(raw string) string {
+	var value strings.Builder
+	keep := 0 // the length of value without trailing unquoted whitespace
+	quoted := false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+			continue
+		case c == '\\' && i+1 < len(raw):
+			i++
+			c = raw[i]
+			switch c {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			}
+		case !quoted && (c == '#' || c == ';'):
+			return value.String()[:keep]
+		case !quoted && (c == ' ' || c == '\t' || c == '\r'):
+			if value.Len() > 0 {
+				value.WriteByte(c)
+			}
+			continue
+		}
+		value.WriteByte(c)
+		keep = value.Len()
+	}
+	return value.String()[:keep]
+}
+
+// Creates a RepoMatcher for the repository in repoRoot
+// the global excludes and the info/exclude of the repository are read immediately, missing files are skipped
+// core.excludesFile in the config of the repository takes precedence over the global one, like in git,
+// a relative core.excludesFile is relative to repoRoot
+// repoRoot can also be a worktree or a submodule, where .git is a file pointing to the git directory
+func NewRepoMatcher(repoRoot string) (*RepoMatcher, error) {
+	gitDir, err := commonGitDir(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var filenames []string
+	global := GlobalExcludesFile()
+	if gitDir != "" {
+		if excludes := configExcludesFile(filepath.Join(gitDir, "config")); excludes != "" {
+			global = excludes
+		}
+	}
+	if global != "" {
+		filenames = append(filenames, resolvePath(repoRoot, global))
+	}
+	if gitDir != "" {
+		filenames = append(filenames, filepath.Join(gitDir, "info", "exclude"))
+	}
+
+	ignores := make([]*GitIgnore, 0, len(filenames))
+	for _, filename := range filenames {
+		ignore, err := CompileIgnoreFile(filename)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ignores = append(ignores, ignore)
+	}
+
+	return &RepoMatcher{
+		root:     repoRoot,
+		excludes: Merge(ignores...),
+		ignores:  make(map[string]*GitIgnore),
+	}, nil
+}
+
+// Reports whether the path, relative to the root of the repository, is ignored
+// like in git, nothing inside an ignored directory can be re-included, and the .gitignore files inside it are not read
+func (m *RepoMatcher) Matches(relPath string, isDir bool) bool {
+	pathComponents, hasSlash, ok := m.excludes.splitPath(relPath)
+	if !ok {
+		return false
+	}
+	isDir = isDir || hasSlash
+
+	for k := 1; k <= len(pathComponents); k++ {
+		ignored := m.decide(pathComponents[:k], k < len(pathComponents) || isDir)
+		if ignored || k == len(pathComponents) {
+			return ignored
+		}
+	}
+	return false
+}
+
+// decides a single entry, the .gitignore of the deepest directory takes precedence
+func (m *RepoMatcher) decide(components []string, isDir bool) bool {
+	for d := len(components) - 1; d >= 0; d-- {
+		ignore := m.gitignore(components[:d])
+		if ignore == nil {
+			continue
+		}
+		if matched, ignored := ignore.decideEntry(components[d:], isDir); matched {
+			return ignored
+		}
+	}
+	_, ignored := m.excludes.decideEntry(components, isDir)
+	return ignored
+}
+
+// returns the rules of the .gitignore in dir, or nil if there is none
+// unreadable files are treated like missing ones
+func (m *RepoMatcher) gitignore(dir []string) *GitIgnore {
+	key := strings.Join(dir, "/")
+	if ignore, ok := m.ignores[key]; ok {
+		return ignore
+	}
+
+	ignore, err := CompileIgnoreFile(filepath.Join(m.root, filepath.FromSlash(key), ".gitignore"))
+	if err != nil || len(ignore.Rules) == 0 {
+		ignore = nil
+	}
+	m.ignores[key] = ignore
+	return ignore
+}
+
+// Finds every .gitignore in the repository, and returns them with the directory they are in as their scope
+// like git, directories that are ignored are not walked, so the .gitignore files inside them are left out
+// the .git directory is skipped, the results are in walk order, so parents come before their subdirectories
+// like for RepoMatcher, .gitignore files without rules or that can't be read are treated like missing ones
+func DiscoverGitignores(repoRoot string) ([]*ScopedGitIgnore, error) {
+	matcher, err := NewRepoMatcher(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var scoped []*ScopedGitIgnore
+	err = filepath.WalkDir(repoRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(repoRoot, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && matcher.Matches(rel, true) {
+			return filepath.SkipDir
+		}
+
+		// the matcher has already compiled the .gitignore files of the parent directories, reuse them
+		var dir []string
+		if rel != "." {
+			dir = strings.Split(rel, "/")
+		}
+		ignore := matcher.gitignore(dir)
+		if ignore == nil {
+			return nil
+		}
+		scoped = append(scoped, NewScopedGitIgnore(rel, ignore))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scoped, nil
+}
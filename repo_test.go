@@ -0,0 +1,189 @@
+package goignore
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writes the files to dir, creating the parent directories, paths with a trailing '/' are created as directories
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(strings.TrimSuffix(name, "/")))
+		var err error
+		if strings.HasSuffix(name, "/") {
+			err = os.MkdirAll(full, 0o755)
+		} else if err = os.MkdirAll(filepath.Dir(full), 0o755); err == nil {
+			err = os.WriteFile(full, []byte(content), 0o644)
+		}
+		if err != nil {
+			t.Fatalf("could not create %s: %v", name, err)
+		}
+	}
+}
+
+func TestRepoMatcher(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	writeTree(t, config, map[string]string{"git/ignore": "*.log\nsecret/\n"})
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".git/info/exclude":  "*.tmp\n!keep.log\n",
+		".gitignore":         "build/\n!important.tmp\n",
+		"src/.gitignore":     "*.o\n!debug.log\n/gen\n",
+		"src/lib/.gitignore": "!*.o\n",
+		"build/.gitignore":   "!*\n",
+	})
+
+	matcher, err := NewRepoMatcher(dir)
+	assert.Nil(t, err, "should create the matcher")
+
+	assert.Equal(t, true, matcher.Matches("a.log", false), "global excludes should apply")
+	assert.Equal(t, false, matcher.Matches("keep.log", false), "info/exclude should override the global excludes")
+	assert.Equal(t, false, matcher.Matches("important.tmp", false), ".gitignore should override info/exclude")
+	assert.Equal(t, true, matcher.Matches("src/a.o", false), "src/.gitignore should apply in src")
+	assert.Equal(t, false, matcher.Matches("test/a.o", false), "src/.gitignore should not apply outside of src")
+	assert.Equal(t, false, matcher.Matches("src/lib/x.o", false), "the deepest .gitignore should take precedence")
+	assert.Equal(t, true, matcher.Matches("src/gen", false), "/gen should be anchored to src")
+	assert.Equal(t, false, matcher.Matches("src/a/gen", false), "/gen should not float")
+	assert.Equal(t, true, matcher.Matches("build/a", false), "build/.gitignore should not be read")
+	assert.Equal(t, true, matcher.Matches("build", true), "should match the directory build")
+	assert.Equal(t, false, matcher.Matches("build", false), "should not match the file build")
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	if err := runGit(dir, "init", "-q"); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+
+	paths := []string{
+		"a.log", "keep.log", "x.tmp", "important.tmp", "secret/", "secret/a", "build/", "build/a", "build/keep.log",
+		"src/a.o", "src/debug.log", "src/x.log", "src/gen", "src/a/gen", "src/lib/x.o", "src/lib/y.log",
+		"src/lib/important.tmp", "docs/a.tmp", "docs/secret/", "docs/secret/b",
+	}
+	for _, path := range paths {
+		writeTree(t, dir, map[string]string{path: ""})
+	}
+	for _, path := range paths {
+		assert.Equal(t, gitCheckIgnored(t, dir, strings.TrimSuffix(path, "/")), matcher.Matches(path, false), "should agree with git on "+path)
+	}
+}
+
+func TestRepoMatcher_GitFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".git/modules/sub/info/exclude": "*.local\n",
+		"sub/.git":                      "gitdir: ../.git/modules/sub\n",
+		"sub/.gitignore":                "build/\n",
+	})
+
+	matcher, err := NewRepoMatcher(filepath.Join(root, "sub"))
+	assert.Nil(t, err, "should create the matcher for a submodule")
+	assert.Equal(t, true, matcher.Matches("a.local", false), "should match a.local from the exclude of the submodule")
+	assert.Equal(t, true, matcher.Matches("build/x", false), "should match build/x from .gitignore")
+	assert.Equal(t, false, matcher.Matches("src/a.go", false), "should not match src/a.go")
+
+	scoped, err := DiscoverGitignores(filepath.Join(root, "sub"))
+	assert.Nil(t, err, "should discover the .gitignore files of a submodule")
+	assert.Equal(t, 1, len(scoped), "should find sub/.gitignore")
+}
+
+func TestRepoMatcher_ExcludesFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	writeTree(t, home, map[string]string{
+		".config/git/ignore": "*.xdg\n",
+		".config/git/config": "[core]\n\texcludesFile = ~/xdg-config-ignore\n",
+		".gitconfig":         "# global\n[user]\n\tname = x\n[Core]\n\texcludesfile = \"~/global ignore\" ; comment\n",
+		"global ignore":      "*.global\n",
+		"xdg-config-ignore":  "*.xdgconfig\n",
+	})
+
+	assert.Equal(t, filepath.Join(home, "global ignore"), GlobalExcludesFile(), "~/.gitconfig should take precedence")
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".config", "git", "config"))
+	assert.Equal(t, filepath.Join(home, "xdg-config-ignore"), GlobalExcludesFile(), "GIT_CONFIG_GLOBAL should replace the global config files")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	assert.Equal(t, filepath.Join(home, ".config", "git", "ignore"), GlobalExcludesFile(), "should fall back to the XDG default")
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{".git/": ""})
+	matcher, err := NewRepoMatcher(dir)
+	assert.Nil(t, err, "should create the matcher")
+	assert.Equal(t, true, matcher.Matches("a.global", false), "should use core.excludesFile of the global config")
+	assert.Equal(t, false, matcher.Matches("a.xdg", false), "core.excludesFile should replace the XDG default")
+
+	writeTree(t, dir, map[string]string{
+		".git/config": "[core]\n\tbare = false\n\texcludesFile = repo-ignore # relative to the work tree\n",
+		"repo-ignore": "*.repo\n",
+	})
+	matcher, err = NewRepoMatcher(dir)
+	assert.Nil(t, err, "should create the matcher")
+	assert.Equal(t, true, matcher.Matches("a.repo", false), "should use core.excludesFile of the repository")
+	assert.Equal(t, false, matcher.Matches("a.global", false), "the repository config should take precedence")
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	if err := runGit(dir, "init", "-q"); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+	for _, path := range []string{"a.repo", "a.global", "a.xdg"} {
+		assert.Equal(t, gitCheckIgnored(t, dir, path), matcher.Matches(path, false), "should agree with git on "+path)
+	}
+}
+
+func TestDiscoverGitignores(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".git/info/exclude":       "excluded/\n",
+		".gitignore":              "build/\n",
+		"build/.gitignore":        "*\n",
+		"src/.gitignore":          "*.o\ngen/\n",
+		"src/gen/.gitignore":      "*\n",
+		"src/lib/.gitignore":      "!*.o\n",
+		"excluded/.gitignore":     "*\n",
+		"docs/notes/":             "",
+		"docs/notes/.gitignore/":  "",
+		"node_modules/.gitignore": "*\n",
+	})
+
+	scoped, err := DiscoverGitignores(dir)
+	assert.Nil(t, err, "should discover the .gitignore files")
+
+	var dirs []string
+	for _, s := range scoped {
+		dirs = append(dirs, s.Dir)
+	}
+	assert.Equal(t, []string{"", "node_modules", "src", "src/lib"}, dirs, "should skip ignored directories")
+	assert.Equal(t, filepath.Join(dir, "src", ".gitignore"), scoped[2].Ignore.Rules[0].Source, "should keep the source of the rules")
+	assert.Equal(t, true, scoped[2].MatchesPath("src/a.o"), "the scope should be ready to use")
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	if err := runGit(dir, "init", "-q"); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+	for _, path := range []string{"build", "src/gen", "excluded"} {
+		assert.Equal(t, true, gitCheckIgnored(t, dir, path), "git should ignore "+path)
+	}
+	for _, path := range []string{"src", "src/lib", "node_modules", "docs/notes"} {
+		assert.Equal(t, false, gitCheckIgnored(t, dir, path), "git should not ignore "+path)
+	}
+}
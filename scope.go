@@ -0,0 +1,50 @@
+package goignore
+
+import (
+	"path"
+	"strings"
+)
+
+// ScopedGitIgnore is a GitIgnore that was read from a .gitignore in a subdirectory of the repository
+// patterns with a slash are anchored to Dir, patterns without one match in Dir and everything beneath it
+// paths outside of Dir are never matched
+type ScopedGitIgnore struct {
+	// the directory of the .gitignore relative to the repository root, using '/' separators, "" for the root
+	Dir string
+	// the rules of the .gitignore
+	Ignore *GitIgnore
+}
+
+// Creates a ScopedGitIgnore for the rules of the .gitignore in dir
+func NewScopedGitIgnore(dir string, g *GitIgnore) *ScopedGitIgnore {
+	dir = strings.Trim(path.Clean(strings.ReplaceAll(dir, "\\", "/")), "/")
+	if dir == "." {
+		dir = ""
+	}
+	return &ScopedGitIgnore{Dir: dir, Ignore: g}
+}
+
+// Same as GitIgnore.MatchesPath, but the path is relative to the repository root, not to Dir
+func (s *ScopedGitIgnore) MatchesPath(path string) bool {
+	return s.MatchesEntry(path, false)
+}
+
+// Same as GitIgnore.MatchesEntry, but the path is relative to the repository root, not to Dir
+func (s *ScopedGitIgnore) MatchesEntry(path string, isDir bool) bool {
+	cleaned, hasSlash, ok := s.Ignore.cleanPath(path)
+	if !ok {
+		return false
+	}
+	if s.Dir != "" {
+		// Dir itself is matched by the .gitignore of its parent, not by its own
+		rel, found := strings.CutPrefix(cleaned, s.Dir+"/")
+		if !found {
+			return false
+		}
+		cleaned = rel
+	}
+
+	pathComponents := mySplitBuf(cleaned, '/', s.Ignore.pathComponentsBuf)
+	deciding := s.Ignore.decidingRule(pathComponents, isDir || hasSlash)
+	return deciding != -1 && !s.Ignore.Rules[deciding].Negate
+}
@@ -0,0 +1,41 @@
+package goignore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopedGitIgnore(t *testing.T) {
+	scoped := NewScopedGitIgnore("src", CompileIgnoreLines([]string{"*.o"}))
+
+	assert.Equal(t, "src", scoped.Dir, "Dir should be src")
+	assert.Equal(t, true, scoped.MatchesPath("src/a.o"), "should match src/a.o")
+	assert.Equal(t, true, scoped.MatchesPath("src/a/b.o"), "should match src/a/b.o")
+	assert.Equal(t, false, scoped.MatchesPath("test/c.o"), "should not match test/c.o")
+	assert.Equal(t, false, scoped.MatchesPath("c.o"), "should not match c.o in the root")
+	assert.Equal(t, false, scoped.MatchesPath("srcx/c.o"), "should not match srcx/c.o")
+	assert.Equal(t, false, scoped.MatchesPath("src/a.c"), "should not match src/a.c")
+}
+
+func TestScopedGitIgnore_Anchored(t *testing.T) {
+	scoped := NewScopedGitIgnore("src/", CompileIgnoreLines([]string{"/build", "gen/out", "tmp/"}))
+
+	assert.Equal(t, "src", scoped.Dir, "Dir should be cleaned")
+	assert.Equal(t, true, scoped.MatchesPath("src/build"), "should match src/build")
+	assert.Equal(t, false, scoped.MatchesPath("src/a/build"), "anchored pattern should not float")
+	assert.Equal(t, true, scoped.MatchesPath("src/gen/out"), "should match src/gen/out")
+	assert.Equal(t, false, scoped.MatchesPath("src/a/gen/out"), "pattern with a slash should be anchored to Dir")
+	assert.Equal(t, true, scoped.MatchesPath("src/a/tmp/"), "should match the directory src/a/tmp")
+	assert.Equal(t, true, scoped.MatchesEntry("src/a/tmp", true), "should match the directory src/a/tmp")
+	assert.Equal(t, false, scoped.MatchesPath("src/a/tmp"), "should not match the file src/a/tmp")
+	assert.Equal(t, false, scoped.MatchesPath("src/"), "should not match Dir itself")
+}
+
+func TestScopedGitIgnore_Root(t *testing.T) {
+	for _, dir := range []string{"", ".", "/"} {
+		scoped := NewScopedGitIgnore(dir, CompileIgnoreLines([]string{"*.o"}))
+		assert.Equal(t, "", scoped.Dir, "root should have an empty Dir")
+		assert.Equal(t, true, scoped.MatchesPath("test/c.o"), "should match test/c.o")
+	}
+}
@@ -0,0 +1,133 @@
+package goignore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrEmptyPattern is returned for patterns that are empty after removing the '!' and '/' markers
+	ErrEmptyPattern = errors.New("empty pattern")
+	// ErrUnclosedBracket is returned for character classes without a closing ']'
+	ErrUnclosedBracket = errors.New("unclosed character class")
+	// ErrReversedRange is returned for ranges like [z-a], that can never match
+	ErrReversedRange = errors.New("reversed character range")
+	// ErrDoubleStarMisuse is returned when "**" is not a whole path component, in which case it acts like a single '*'
+	ErrDoubleStarMisuse = errors.New(`"**" must be a whole path component`)
+	// ErrTrailingBackslash is returned when a path component of a pattern ends in a '\\' that doesn't escape anything,
+	// outside of strict mode such a '\\' matches a literal '\\'
+	ErrTrailingBackslash = errors.New("trailing backslash")
+)
+
+// Checks a single line of a .gitignore file for mistakes
+// blank lines and comments are valid, errors wrap one of the Err* variables, so they can be checked with errors.Is
+// it does not allocate for valid patterns
+func ValidatePattern(pattern string) error {
+	return validatePattern(pattern, isDefaultComment)
+}
+
+// reports whether the trimmed, non-empty line is a comment with the default "#" prefix
+func isDefaultComment(line string) bool {
+	return line[0] == '#'
+}
+
+// same as ValidatePattern, but isComment tells which trimmed, non-empty lines are comments
+func validatePattern(pattern string, isComment func(string) bool) error {
+	pattern = trimLine(pattern)
+	if pattern == "" || isComment(pattern) {
+		return nil
+	}
+
+	body := pattern
+	if body[0] == '!' {
+		body = body[1:]
+	}
+	body = strings.Trim(body, "/")
+	if body == "" {
+		return fmt.Errorf("%w: %q", ErrEmptyPattern, pattern)
+	}
+
+	for body != "" {
+		component := body
+		if i := strings.IndexByte(body, '/'); i != -1 {
+			component, body = body[:i], body[i+1:]
+		} else {
+			body = ""
+		}
+		if err := validateComponent(component); err != nil {
+			return fmt.Errorf("%w in %q", err, pattern)
+		}
+	}
+	return nil
+}
+
+// checks a single path component of a pattern
+func validateComponent(component string) error {
+	for i := 0; i < len(component); i++ {
+		switch component[i] {
+		case '\\':
+			if i == len(component)-1 {
+				return ErrTrailingBackslash
+			}
+			i++ // skip the escaped character
+		case '*':
+			if i+1 < len(component) && component[i+1] == '*' && component != "**" {
+				return ErrDoubleStarMisuse
+			}
+		case '[':
+			end, err := validateCharClass(component, i)
+			if err != nil {
+				return err
+			}
+			i = end
+		}
+	}
+	return nil
+}
+
+// checks the character class starting at index start, this follows the logic of stringMatch
+// returns the index of the closing ']'
+func validateCharClass(component string, start int) (int, error) {
+	j := start + 1 // skip '['
+	if j < len(component) && (component[j] == '!' || component[j] == '^') {
+		j++
+	}
+	// a leading ']' is a literal
+	if j < len(component) && component[j] == ']' {
+		j++
+	}
+
+	for j < len(component) && component[j] != ']' {
+		if j+2 < len(component) && component[j] == '[' && component[j+1] == ':' {
+			end := strings.Index(component[j+2:], ":]")
+			if end < 1 {
+				return 0, ErrUnclosedBracket
+			}
+			j += 2 + end + 2
+			continue
+		}
+		a, width := component[j], 1
+		if a == '\\' && j+1 < len(component) {
+			a, width = component[j+1], 2
+		}
+		if k := j + width + 1; k < len(component) && component[j+width] == '-' && component[k] != ']' {
+			b := component[k]
+			if b == '\\' && k+1 < len(component) {
+				k++
+				b = component[k]
+			}
+			if a > b {
+				return 0, fmt.Errorf("%w %q", ErrReversedRange, component[j:k+1])
+			}
+			j = k + 1
+			continue
+		}
+		j += width
+	}
+
+	if j >= len(component) {
+		return 0, ErrUnclosedBracket
+	}
+	return j, nil
+}
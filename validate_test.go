@@ -0,0 +1,48 @@
+package goignore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePattern(t *testing.T) {
+	valid := []string{
+		"", "   ", "# comment", "*.log", "!keep.log", "/build/", "**/foo", "foo/**", "a/**/b",
+		"[a-z]*.txt", "[!0-9]", "[]-]", "[a-]", "[[:digit:]].txt", "\\[hello", "\\!file", "my\\ folder/file",
+		"foo\\ ", "foo\\ \r\n", "foo\\\\ ",
+	}
+	for _, pattern := range valid {
+		assert.Nil(t, ValidatePattern(pattern), "should accept "+pattern)
+	}
+
+	invalid := map[string]error{
+		"!":             ErrEmptyPattern,
+		"/":             ErrEmptyPattern,
+		"!/":            ErrEmptyPattern,
+		"//":            ErrEmptyPattern,
+		"[abc":          ErrUnclosedBracket,
+		"foo/[a-z":      ErrUnclosedBracket,
+		"*[*":           ErrUnclosedBracket,
+		"[[:alpha:]":    ErrUnclosedBracket,
+		"[[:alpha]].md": ErrUnclosedBracket,
+		"[z-a]":         ErrReversedRange,
+		"file[9-0].txt": ErrReversedRange,
+		"[\\z-a]":       ErrReversedRange,
+		"a**b":          ErrDoubleStarMisuse,
+		"**foo":         ErrDoubleStarMisuse,
+		"foo/***/bar":   ErrDoubleStarMisuse,
+		"foo\\":         ErrTrailingBackslash,
+		"foo\\/bar":     ErrTrailingBackslash,
+	}
+	for pattern, expected := range invalid {
+		assert.ErrorIs(t, ValidatePattern(pattern), expected, "should reject "+pattern)
+	}
+}
+
+func TestValidatePattern_Allocations(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = ValidatePattern("!/src/**/[a-z]*[[:digit:]].go")
+	})
+	assert.Equal(t, 0.0, allocs, "should not allocate for valid patterns")
+}
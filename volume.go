@@ -0,0 +1,47 @@
+package goignore
+
+import "strings"
+
+func isSeparator(c byte) bool {
+	return c == '\\' || c == '/'
+}
+
+// skips n components of a path, including the separators after them
+func skipComponents(path string, n int) string {
+	for ; n > 0 && path != ""; n-- {
+		i := strings.IndexAny(path, `\/`)
+		if i == -1 {
+			return ""
+		}
+		path = path[i+1:]
+	}
+	return path
+}
+
+// Removes the Windows volume from the path, so the rest of it can be matched as a relative path
+// handles drive letters (C:\a), UNC paths (\\server\share\a) and their long forms (\\?\C:\a, \\?\UNC\server\share\a)
+// the separators of the rest of the path are left untouched, paths without a volume are returned unchanged
+func StripVolume(path string) string {
+	stripped := path
+	if len(stripped) >= 4 && isSeparator(stripped[0]) && isSeparator(stripped[1]) &&
+		(stripped[2] == '?' || stripped[2] == '.') && isSeparator(stripped[3]) {
+		// long path prefix
+		stripped = stripped[4:]
+		if len(stripped) >= 4 && strings.EqualFold(stripped[:3], "UNC") && isSeparator(stripped[3]) {
+			stripped = skipComponents(stripped[4:], 2)
+		}
+	} else if len(stripped) >= 2 && isSeparator(stripped[0]) && isSeparator(stripped[1]) {
+		// UNC path, skip the server and the share
+		stripped = skipComponents(stripped[2:], 2)
+	}
+
+	if len(stripped) >= 2 && stripped[1] == ':' &&
+		('a' <= stripped[0] && stripped[0] <= 'z' || 'A' <= stripped[0] && stripped[0] <= 'Z') {
+		stripped = stripped[2:]
+	} else if len(stripped) == len(path) {
+		// no volume
+		return path
+	}
+
+	return strings.TrimLeft(stripped, `\/`)
+}
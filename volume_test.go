@@ -0,0 +1,33 @@
+package goignore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripVolume(t *testing.T) {
+	assert.Equal(t, `a\b`, StripVolume(`C:\a\b`), "should strip the drive letter")
+	assert.Equal(t, "a/b", StripVolume("c:/a/b"), "should strip a lowercase drive letter")
+	assert.Equal(t, `a\b`, StripVolume(`\\srv\sh\a\b`), "should strip the UNC prefix")
+	assert.Equal(t, "a/b", StripVolume("//srv/sh/a/b"), "should strip a UNC prefix with forward slashes")
+	assert.Equal(t, `a\b`, StripVolume(`\\?\C:\a\b`), "should strip a long drive path prefix")
+	assert.Equal(t, `a\b`, StripVolume(`\\?\UNC\srv\sh\a\b`), "should strip a long UNC path prefix")
+	assert.Equal(t, "", StripVolume(`\\srv\sh`), "should strip a bare share")
+	assert.Equal(t, "a/b", StripVolume("a/b"), "should leave relative paths unchanged")
+	assert.Equal(t, `a\b`, StripVolume(`a\b`), "should leave relative Windows paths unchanged")
+	assert.Equal(t, "/a/b", StripVolume("/a/b"), "should leave absolute paths without a volume unchanged")
+}
+
+func TestWindowsPaths(t *testing.T) {
+	gitIgnore := []string{"/proj/build/", "*.log"}
+
+	ignoreObject := CompileIgnoreLinesWithOptions(gitIgnore, Options{WindowsPaths: true})
+	assert.NotNil(t, ignoreObject, "Returned object should not be nil")
+	assert.Equal(t, true, ignoreObject.MatchesPath(`C:\proj\build\x`), "should match C:\\proj\\build\\x")
+	assert.Equal(t, true, ignoreObject.MatchesPath(`\\srv\sh\proj\build\x`), "should match \\\\srv\\sh\\proj\\build\\x")
+	assert.Equal(t, true, ignoreObject.MatchesPath(`proj\build\`), "trailing backslash should mark a directory")
+	assert.Equal(t, false, ignoreObject.MatchesPath(`proj\build`), "should not match the file proj\\build")
+	assert.Equal(t, true, ignoreObject.MatchesPath(`D:\a\b.log`), "should match D:\\a\\b.log")
+	assert.Equal(t, false, ignoreObject.MatchesPath(`C:\proj\src\main.go`), "should not match C:\\proj\\src\\main.go")
+}
@@ -0,0 +1,147 @@
+package goignore
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// WalkMatcher wraps a GitIgnore, and caches the decisions for ignored directories
+// once a directory is known to be ignored, its descendants are ignored without evaluating the rules again,
+// unless a negation after the deciding rule could re-include them, in which case they are matched normally
+// directories are only cached when they are queried with a trailing '/', the way a walk visits them
+// a WalkMatcher is not safe for concurrent use
+type WalkMatcher struct {
+	ignore       *GitIgnore
+	lastNegation int
+	ignoredDirs  map[string]struct{}
+}
+
+// Creates a WalkMatcher for the gitignore
+// the gitignore must not be modified while the WalkMatcher is in use
+func NewWalkMatcher(g *GitIgnore) *WalkMatcher {
+	lastNegation := -1
+	for i := range g.Rules {
+		if g.Rules[i].Negate {
+			lastNegation = i
+		}
+	}
+	if g.options.RegexMode {
+		// a regex that matches a directory doesn't have to match its contents, so nothing can be cached
+		lastNegation = len(g.Rules)
+	}
+
+	return &WalkMatcher{
+		ignore:       g,
+		lastNegation: lastNegation,
+		ignoredDirs:  make(map[string]struct{}),
+	}
+}
+
+// Same as GitIgnore.MatchesPath, but uses the cached decisions of the parent directories
+func (w *WalkMatcher) MatchesPath(path string) bool {
+	path, isDir, ok := w.ignore.cleanPath(path)
+	if !ok {
+		return false
+	}
+
+	// check if any of the parent directories is known to be ignored
+	for i := strings.IndexByte(path, '/'); i != -1; i = next(path, i) {
+		if _, ignored := w.ignoredDirs[path[:i]]; ignored {
+			return true
+		}
+	}
+
+	pathComponents := mySplitBuf(path, '/', w.ignore.pathComponentsBuf)
+	deciding := w.ignore.decidingRule(pathComponents, isDir)
+	if deciding == -1 || w.ignore.Rules[deciding].Negate {
+		return false
+	}
+
+	// every rule that matches a directory also matches its descendants,
+	// so only a later negation could change the decision for them
+	if isDir && deciding > w.lastNegation {
+		w.ignoredDirs[path] = struct{}{}
+	}
+	return true
+}
+
+// returns the index of the next '/' in path after i, or -1
+func next(path string, i int) int {
+	j := strings.IndexByte(path[i+1:], '/')
+	if j == -1 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+// WalkFunc is the type of the function called by WalkDir for every entry
+// ignored reports whether the gitignore matches the entry, the rest is the same as for fs.WalkDirFunc
+type WalkFunc func(path string, d fs.DirEntry, ignored bool, err error) error
+
+// Reports whether the directory is ignored, and nothing inside it can be re-included by a negation
+// a walk can skip such a directory without missing any path that is not ignored
+func (g *GitIgnore) CanSkipDir(dir string) bool {
+	pathComponents, _, ok := g.splitPath(dir)
+	if !ok || g.options.RegexMode {
+		return false
+	}
+	deciding := g.decidingRule(pathComponents, true)
+	if deciding == -1 || g.Rules[deciding].Negate {
+		return false
+	}
+
+	// the deciding rule matches every descendant, so only a later negation could re-include one
+	for i := deciding + 1; i < len(g.Rules); i++ {
+		if g.Rules[i].Negate && g.Rules[i].couldMatchInside(pathComponents, &g.options) {
+			return false
+		}
+	}
+	return true
+}
+
+// reports whether the rule could match a descendant of the directory
+func (r *Rule) couldMatchInside(dirComponents []string, options *Options) bool {
+	if !r.Relative {
+		// the last component of a floating rule can match any name
+		return true
+	}
+	for i, component := range dirComponents {
+		if i >= len(r.Components) {
+			// the rule matches the directory or one of its parents, and that includes the descendants
+			return true
+		}
+		if r.Components[i] == "**" {
+			return true
+		}
+		if !stringMatchFold(component, r.Components[i], options.FoldDirectories, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Walks the file tree rooted at root like fs.WalkDir, and reports whether each entry is ignored
+// the paths are matched relative to root, so the gitignore should be the one in root, root itself is never ignored
+// ignored directories that CanSkipDir reports as skippable are reported once, and their contents are skipped,
+// other ignored directories are walked, so the paths a negation re-includes inside them are found
+// returning fs.SkipDir from fn skips the directory, the same as for fs.WalkDir
+func (g *GitIgnore) WalkDir(fsys fs.FS, root string, fn WalkFunc) error {
+	return fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if path == root || d == nil {
+			return fn(path, d, false, err)
+		}
+		rel := path
+		if root != "." {
+			rel = strings.TrimPrefix(path, root+"/")
+		}
+
+		ignored := g.MatchesEntry(rel, d.IsDir())
+		if err := fn(path, d, ignored, err); err != nil {
+			return err
+		}
+		if ignored && d.IsDir() && g.CanSkipDir(rel) {
+			return fs.SkipDir
+		}
+		return nil
+	})
+}
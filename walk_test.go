@@ -0,0 +1,168 @@
+package goignore
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalkMatcher(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"node_modules/", "*.log", "build/"})
+	walkMatcher := NewWalkMatcher(ignoreObject)
+
+	assert.NotNil(t, walkMatcher, "Returned object should not be nil")
+	assert.Equal(t, true, walkMatcher.MatchesPath("node_modules/"), "should match node_modules/")
+	assert.Equal(t, true, walkMatcher.MatchesPath("node_modules/a/b/c.js"), "should match descendants of node_modules")
+	assert.Equal(t, true, walkMatcher.MatchesPath("a.log"), "should match a.log")
+	assert.Equal(t, false, walkMatcher.MatchesPath("src/"), "should not match src/")
+	assert.Equal(t, false, walkMatcher.MatchesPath("src/main.go"), "should not match src/main.go")
+	assert.Equal(t, false, walkMatcher.MatchesPath("node_modules"), "should not match the file node_modules")
+	assert.Equal(t, false, walkMatcher.MatchesPath("node_modules_2/x"), "should not match node_modules_2/x")
+}
+
+func TestWalkMatcher_Negations(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"build/", "!build/keep", "cache/", "tmp/"})
+	walkMatcher := NewWalkMatcher(ignoreObject)
+
+	paths := []string{
+		"build/", "build/a", "build/keep", "build/keep/x",
+		"cache/", "cache/a/", "cache/a/b", "cache/keep",
+		"tmp/", "tmp/x", "src/", "src/build/", "src/build/keep",
+	}
+	// run twice, so the second run uses the cache
+	for i := 0; i < 2; i++ {
+		for _, path := range paths {
+			assert.Equal(t, ignoreObject.MatchesPath(path), walkMatcher.MatchesPath(path), "should agree with MatchesPath on "+path)
+		}
+	}
+	assert.Equal(t, false, walkMatcher.MatchesPath("build/keep"), "negation should still re-include build/keep")
+	assert.Equal(t, true, walkMatcher.MatchesPath("cache/a/b"), "should match cache/a/b")
+}
+
+// walks fsys with WalkDir, and returns the paths that are not ignored, directories have a trailing '/'
+func walkKept(t *testing.T, ignoreObject *GitIgnore, fsys fs.FS, root string) []string {
+	var kept []string
+	err := ignoreObject.WalkDir(fsys, root, func(path string, d fs.DirEntry, ignored bool, err error) error {
+		if err != nil {
+			return err
+		}
+		if !ignored {
+			if d.IsDir() {
+				path += "/"
+			}
+			kept = append(kept, path)
+		}
+		return nil
+	})
+	assert.Nil(t, err, "should walk the tree")
+	return kept
+}
+
+var walkTree = fstest.MapFS{
+	"main.go":         {},
+	"a.log":           {},
+	"build/out":       {},
+	"build/keep":      {},
+	"src/build":       {},
+	"src/lib/util.go": {},
+	"src/lib/x.log":   {},
+}
+
+func TestWalkDir(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"*.log", "build/"})
+
+	assert.Equal(t, []string{"./", "main.go", "src/", "src/build", "src/lib/", "src/lib/util.go"},
+		walkKept(t, ignoreObject, walkTree, "."), "should only report the entries that are not ignored")
+	assert.Equal(t, []string{"src/", "src/build", "src/lib/", "src/lib/util.go"},
+		walkKept(t, ignoreObject, walkTree, "src"), "should match relative to root")
+}
+
+func TestCanSkipDir(t *testing.T) {
+	ignoreObject := CompileIgnoreLines([]string{"build/", "!src/keep", "cache/", "!cache/**/*.txt"})
+
+	assert.Equal(t, true, ignoreObject.CanSkipDir("build"), "nothing can re-include anything in build")
+	assert.Equal(t, false, ignoreObject.CanSkipDir("src"), "src is not ignored")
+	assert.Equal(t, false, ignoreObject.CanSkipDir("cache"), "!cache/**/*.txt could re-include something in cache")
+	assert.Equal(t, false, CompileIgnoreLines([]string{"tmp/", "!keep"}).CanSkipDir("tmp"), "!keep could re-include tmp/keep")
+
+	assert.Equal(t, true, CompileIgnoreLines([]string{"!keep", "build/"}).CanSkipDir("build"), "an earlier negation is overridden")
+	assert.Equal(t, false, CompileIgnoreLines([]string{"build/", "!build/"}).CanSkipDir("build"), "build is re-included")
+	assert.Equal(t, false, CompileIgnoreLines([]string{"build/", "!/build/keep"}).CanSkipDir("build"), "!/build/keep could re-include build/keep")
+	assert.Equal(t, true, CompileIgnoreLines([]string{"build/", "!/build/keep"}).CanSkipDir("a/build"), "!/build/keep can't match inside a/build")
+	assert.Equal(t, false, CompileIgnoreLinesWithOptions([]string{"build/", "!/BUILD/keep"}, Options{FoldDirectories: true}).CanSkipDir("build"), "folding should apply")
+}
+
+func TestWalkDir_Pruning(t *testing.T) {
+	visit := func(ignoreObject *GitIgnore) (visited []string, kept []string) {
+		err := ignoreObject.WalkDir(walkTree, ".", func(path string, d fs.DirEntry, ignored bool, err error) error {
+			visited = append(visited, path)
+			if !ignored {
+				kept = append(kept, path)
+			}
+			return err
+		})
+		assert.Nil(t, err, "should walk the tree")
+		return visited, kept
+	}
+
+	visited, kept := visit(CompileIgnoreLines([]string{"build/"}))
+	assert.Contains(t, visited, "build", "should report build once")
+	assert.NotContains(t, visited, "build/out", "should not descend into build")
+	assert.NotContains(t, kept, "build", "should report build as ignored")
+
+	visited, kept = visit(CompileIgnoreLines([]string{"build/", "!build/keep"}))
+	assert.Contains(t, visited, "build/out", "should descend into build")
+	assert.NotContains(t, kept, "build/out", "should still ignore build/out")
+	assert.Contains(t, kept, "build/keep", "should find build/keep")
+}
+
+// generates the paths of a deep directory tree, directories have a trailing '/'
+func deepTree(root string, depth int, width int) []string {
+	paths := []string{root + "/"}
+	dirs := []string{root}
+	for d := 0; d < depth; d++ {
+		var next []string
+		for _, dir := range dirs {
+			for w := 0; w < width; w++ {
+				child := dir + "/d" + strings.Repeat("x", w)
+				paths = append(paths, child+"/", child+"/index.js")
+				next = append(next, child)
+			}
+		}
+		dirs = next
+	}
+	return paths
+}
+
+var benchmarkRules = []string{
+	"*.log", "!important.log", "node_modules/", "*.tmp", "/dist", "coverage/", "**/.cache", "*.[oa]",
+	".env", "*.swp", "build/", "/vendor/**", "**/target/**", "*.class",
+}
+
+func BenchmarkMatchesPath_DeepIgnoredTree(b *testing.B) {
+	ignoreObject := CompileIgnoreLines(benchmarkRules)
+	paths := deepTree("node_modules", 5, 4)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, path := range paths {
+			ignoreObject.MatchesPath(path)
+		}
+	}
+}
+
+func BenchmarkWalkMatcher_DeepIgnoredTree(b *testing.B) {
+	ignoreObject := CompileIgnoreLines(benchmarkRules)
+	paths := deepTree("node_modules", 5, 4)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		walkMatcher := NewWalkMatcher(ignoreObject)
+		for _, path := range paths {
+			walkMatcher.MatchesPath(path)
+		}
+	}
+}